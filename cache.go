@@ -0,0 +1,325 @@
+package awql
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheMode defines how a query uses the cache.
+type CacheMode int
+
+// List of cache modes.
+const (
+	// CacheUse reads the report in cache if it is still fresh, otherwise it downloads and caches it.
+	CacheUse CacheMode = iota
+	// CacheBypass ignores the cache: the report is downloaded and not cached.
+	CacheBypass
+	// CacheRefresh forces the download of the report and replaces the cached one.
+	CacheRefresh
+)
+
+// cacheModes lists the cache modes by name, as used in the data source name.
+var cacheModes = map[string]CacheMode{
+	"use":     CacheUse,
+	"bypass":  CacheBypass,
+	"refresh": CacheRefresh,
+}
+
+// String returns the name of the cache mode.
+func (m CacheMode) String() string {
+	for name, v := range cacheModes {
+		if v == m {
+			return name
+		}
+	}
+	return strconv.Itoa(int(m))
+}
+
+// cacheDir is the name of the default directory of the cache, in the directory for temporary files.
+const cacheDir = "awql-driver"
+
+// Cache stores on disk the reports downloaded from the Adwords API.
+// Each report is kept in a file named with the hash of its query.
+// A cached report expires after its TTL, a zero TTL meaning that it never expires
+// and a negative one that it is already expired.
+// Once the total size of the cache exceeds MaxSize, the least recently used reports are evicted.
+// A zero MaxSize means no limit.
+type Cache struct {
+	Dir     string
+	TTL     time.Duration
+	MaxSize int64
+
+	once  sync.Once
+	mu    sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+// cacheEntry represents a cached report.
+type cacheEntry struct {
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+// NewCache returns a new instance of Cache.
+// If dir is empty, the cache uses a dedicated directory named awql-driver
+// in the default directory for temporary files.
+func NewCache(dir string, ttl time.Duration, maxSize int64) *Cache {
+	return &Cache{Dir: dir, TTL: ttl, MaxSize: maxSize}
+}
+
+// Len returns the number of reports in cache.
+func (c *Cache) Len() int {
+	c.once.Do(c.load)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Size returns the total size in bytes of the reports in cache.
+func (c *Cache) Size() int64 {
+	c.once.Do(c.load)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Purge removes all the reports in cache.
+func (c *Cache) Purge() error {
+	c.once.Do(c.load)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		if rerr := os.Remove(c.path(e.Value.(*cacheEntry).key)); rerr != nil && !os.IsNotExist(rerr) {
+			err = rerr
+		}
+	}
+	c.reset()
+	return err
+}
+
+// dir returns the directory of the cache.
+func (c *Cache) dir() string {
+	if c.Dir == "" {
+		return filepath.Join(os.TempDir(), cacheDir)
+	}
+	return c.Dir
+}
+
+// path returns the file path of the report with this key.
+// @example /tmp/awql-driver/awql16027257112758723916.csv
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir(), "awql"+key+"."+strings.ToLower(apiFmt))
+}
+
+// reset empties the index of the cache.
+func (c *Cache) reset() {
+	c.lru = list.New()
+	c.items = make(map[string]*list.Element)
+	c.size = 0
+}
+
+// load indexes the reports already in the cache directory,
+// the most recently modified being considered as the most recently used.
+func (c *Cache) load() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reset()
+	fs, err := ioutil.ReadDir(c.dir())
+	if err != nil {
+		return
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		return fs[i].ModTime().After(fs[j].ModTime())
+	})
+	ext := "." + strings.ToLower(apiFmt)
+	for _, f := range fs {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, "awql") || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ce := &cacheEntry{
+			key:     strings.TrimSuffix(strings.TrimPrefix(name, "awql"), ext),
+			size:    f.Size(),
+			modTime: f.ModTime(),
+		}
+		c.items[ce.key] = c.lru.PushBack(ce)
+		c.size += ce.size
+	}
+}
+
+// remove deletes the entry of the cache and its file.
+// Lock must be held by the caller.
+func (c *Cache) remove(e *list.Element) {
+	ce := c.lru.Remove(e).(*cacheEntry)
+	delete(c.items, ce.key)
+	c.size -= ce.size
+	_ = os.Remove(c.path(ce.key))
+}
+
+// get returns the report with this key if it exists and its age does not exceed the TTL.
+// A zero TTL means the default TTL of the cache, a negative one that the report is expired.
+func (c *Cache) get(key string, ttl time.Duration) (io.ReadCloser, bool) {
+	c.once.Do(c.load)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if ttl == 0 {
+		ttl = c.TTL
+	}
+	if ttl < 0 || ttl > 0 && time.Since(e.Value.(*cacheEntry).modTime) > ttl {
+		// Expired report.
+		c.remove(e)
+		return nil, false
+	}
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+
+	return f, true
+}
+
+// put returns a reader that caches the report with this key while reading it.
+// The report is only cached if it has been entirely read before closing the reader.
+func (c *Cache) put(key string, rc io.ReadCloser) (io.ReadCloser, error) {
+	c.once.Do(c.load)
+	if err := os.MkdirAll(c.dir(), 0700); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(c.dir(), "awql*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &cacheWriter{rc: rc, f: f, c: c, key: key}, nil
+}
+
+// commit moves the temporary file as report with this key.
+// It evicts the least recently used reports if the cache exceeds its size.
+func (c *Cache) commit(tmp, key string) error {
+	fi, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		return err
+	}
+	if e, ok := c.items[key]; ok {
+		c.size -= e.Value.(*cacheEntry).size
+		c.lru.Remove(e)
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, size: fi.Size(), modTime: time.Now()})
+	c.size += fi.Size()
+
+	for c.MaxSize > 0 && c.size > c.MaxSize && c.lru.Len() > 1 {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+// cacheWriter copies in a temporary file all the data read from the report.
+type cacheWriter struct {
+	rc       io.ReadCloser
+	f        *os.File
+	c        *Cache
+	key      string
+	eof      bool
+	writeErr error
+}
+
+// Read implements the io.Reader interface.
+func (w *cacheWriter) Read(p []byte) (int, error) {
+	n, err := w.rc.Read(p)
+	if n > 0 && w.writeErr == nil {
+		_, w.writeErr = w.f.Write(p[:n])
+	}
+	if err == io.EOF {
+		w.eof = true
+	}
+	return n, err
+}
+
+// Close closes the report and caches it if it has been entirely read.
+func (w *cacheWriter) Close() error {
+	err := w.rc.Close()
+	if cerr := w.f.Close(); cerr != nil && w.writeErr == nil {
+		w.writeErr = cerr
+	}
+	if !w.eof || w.writeErr != nil || w.c.commit(w.f.Name(), w.key) != nil {
+		_ = os.Remove(w.f.Name())
+	}
+	return err
+}
+
+// cacheKey returns the key of the report of this query for the given account and API options.
+func cacheKey(adwordsID string, o *Opts, q string) string {
+	h := fnv.New64()
+	for _, s := range []string{
+		adwordsID,
+		o.Version,
+		strconv.FormatBool(o.IncludeZeroImpressions),
+		strconv.FormatBool(o.SkipColumnHeader),
+		strconv.FormatBool(o.SkipReportHeader),
+		strconv.FormatBool(o.SkipReportSummary),
+		strconv.FormatBool(o.UseRawEnumValues),
+		strings.TrimSpace(q),
+	} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	return strconv.FormatUint(h.Sum64(), 10)
+}
+
+// cacheCtxKey is the type of the context keys used to control the cache.
+type cacheCtxKey int
+
+const (
+	cacheModeKey cacheCtxKey = iota
+	cacheTTLKey
+)
+
+// ContextWithCacheMode returns a copy of the context in which the queries use the cache with this mode.
+// It overrides the cache mode of the connection.
+func ContextWithCacheMode(ctx context.Context, m CacheMode) context.Context {
+	return context.WithValue(ctx, cacheModeKey, m)
+}
+
+// ContextWithCacheTTL returns a copy of the context in which the queries only use
+// the cached reports younger than this TTL. It overrides the TTL of the cache.
+// A negative TTL expires the cached reports: the queries download them again and refresh the cache.
+func ContextWithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheTTLKey, ttl)
+}
+
+// cacheControl returns the cache mode and TTL to use with this context.
+func cacheControl(ctx context.Context, m CacheMode) (CacheMode, time.Duration) {
+	if v, ok := ctx.Value(cacheModeKey).(CacheMode); ok {
+		m = v
+	}
+	ttl, _ := ctx.Value(cacheTTLKey).(time.Duration)
+	return m, ttl
+}
@@ -0,0 +1,191 @@
+package awql_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// newCachedDB returns a database using the given cache and recording the requests to the API.
+func newCachedDB(t *testing.T, cache *awql.Cache, rqs *[]*http.Request) *sql.DB {
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	return sql.OpenDB(newConnector(
+		t, dsn,
+		awql.WithHTTPClient(newClient(http.StatusOK, "Account\nRv\n", rqs)),
+		awql.WithCache(cache),
+	))
+}
+
+// queryAll executes the query and reads all its rows.
+func queryAll(ctx context.Context, db *sql.DB, q string) error {
+	rs, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+	for rs.Next() {
+	}
+	return rs.Err()
+}
+
+// TestCache tests the usage of the cache by the queries.
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		rqs   []*http.Request
+		cache = awql.NewCache(dir, time.Hour, 0)
+		db    = newCachedDB(t, cache, &rqs)
+		ctx   = context.Background()
+		q     = "SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT"
+	)
+	defer db.Close()
+
+	var cacheTests = []struct {
+		ctx context.Context
+		rqs int
+		len int
+	}{
+		// Downloads then caches the report.
+		{ctx: ctx, rqs: 1, len: 1},
+		// Uses the cache.
+		{ctx: ctx, rqs: 1, len: 1},
+		// Bypasses the cache.
+		{ctx: awql.ContextWithCacheMode(ctx, awql.CacheBypass), rqs: 2, len: 1},
+		// Refreshes the cache.
+		{ctx: awql.ContextWithCacheMode(ctx, awql.CacheRefresh), rqs: 3, len: 1},
+		// Expires the cached report.
+		{ctx: awql.ContextWithCacheTTL(ctx, time.Nanosecond), rqs: 4, len: 1},
+		{ctx: ctx, rqs: 4, len: 1},
+		// A negative TTL expires the cached report.
+		{ctx: awql.ContextWithCacheTTL(ctx, -time.Hour), rqs: 5, len: 1},
+		{ctx: ctx, rqs: 5, len: 1},
+	}
+	for i, ct := range cacheTests {
+		if err := queryAll(ct.ctx, db, q); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if len(rqs) != ct.rqs {
+			t.Errorf("%d. Expected %d requests, received %d", i, ct.rqs, len(rqs))
+		}
+		if l := cache.Len(); l != ct.len {
+			t.Errorf("%d. Expected %d reports in cache, received %d", i, ct.len, l)
+		}
+	}
+	// Reloads the cache from its directory.
+	c := awql.NewCache(dir, 0, 0)
+	if c.Len() != 1 || c.Size() != cache.Size() {
+		t.Errorf("Expected the cached report on disk, received %d reports", c.Len())
+	}
+	if err := c.Purge(); err != nil || c.Len() != 0 {
+		t.Errorf("Expected no report after purging the cache, received %d reports and %v", c.Len(), err)
+	}
+}
+
+// TestCache_MaxSize tests the eviction of the least recently used reports.
+func TestCache_MaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		rqs   []*http.Request
+		cache = awql.NewCache(dir, 0, 20)
+		db    = newCachedDB(t, cache, &rqs)
+		ctx   = context.Background()
+	)
+	defer db.Close()
+
+	for i, q := range []string{
+		"SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT",
+		"SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT DURING TODAY",
+		"SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT DURING YESTERDAY",
+	} {
+		if err := queryAll(ctx, db, q); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if s := cache.Size(); s > 20 {
+			t.Errorf("%d. Expected a cache size under 20 bytes, received %d", i, s)
+		}
+	}
+	if l := cache.Len(); l != 1 {
+		t.Errorf("Expected only the last report in cache, received %d", l)
+	}
+}
+
+// TestCache_Partial tests that a report partially read is not cached.
+func TestCache_Partial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		rqs   []*http.Request
+		cache = awql.NewCache(dir, 0, 0)
+		db    = newCachedDB(t, cache, &rqs)
+	)
+	defer db.Close()
+
+	rs, err := db.Query("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatalf("Expected no error on close, received %v", err)
+	}
+	if l := cache.Len(); l != 0 {
+		t.Errorf("Expected no report in cache, received %d", l)
+	}
+}
+
+// TestNewCache_DefaultDir tests the dedicated directory used by default.
+func TestNewCache_DefaultDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	_ = os.Setenv("TMPDIR", tmp)
+
+	// Report of another program in the directory for temporary files.
+	other := filepath.Join(tmp, "awql123.csv")
+	if err := ioutil.WriteFile(other, []byte("Account\nRv\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		rqs   []*http.Request
+		cache = awql.NewCache("", 0, 0)
+		db    = newCachedDB(t, cache, &rqs)
+	)
+	defer db.Close()
+
+	if err := queryAll(context.Background(), db, "SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT"); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	fi, err := os.Stat(filepath.Join(tmp, "awql-driver"))
+	if err != nil || !fi.IsDir() || fi.Mode().Perm() != 0700 {
+		t.Fatalf("Expected the dedicated directory of the cache, received %v", err)
+	}
+	if err := cache.Purge(); err != nil || cache.Len() != 0 {
+		t.Errorf("Expected no report after purging the cache, received %d reports and %v", cache.Len(), err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected the file of another program kept, received %v", err)
+	}
+}
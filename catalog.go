@@ -0,0 +1,198 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Field types of the Adwords API.
+const (
+	typeBoolean = "Boolean"
+	typeDate    = "Date"
+	typeDouble  = "Double"
+	typeEnum    = "Enum"
+	typeInteger = "Integer"
+	typeList    = "List"
+	typeLong    = "Long"
+	typeMoney   = "Money"
+	typeString  = "String"
+)
+
+// Field behaviors of the Adwords API.
+const (
+	behaviorAttribute = "ATTRIBUTE"
+	behaviorMetric    = "METRIC"
+	behaviorSegment   = "SEGMENT"
+)
+
+// Placeholder used by the Adwords API for the missing values.
+const nullValue = "--"
+
+// Date format used in the reports.
+const dateLayout = "2006-01-02"
+
+// reports contains the report definitions of each supported API version.
+//
+//go:embed reports/*.json
+var reports embed.FS
+
+// field represents a field of a report definition.
+// By default, a field can be selected and filtered, and is compatible with any other field.
+// ExclusiveFields lists the fields which can not be selected or filtered with it.
+type field struct {
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"displayName"`
+	Type            string   `json:"type"`
+	Behavior        string   `json:"behavior"`
+	NotSelectable   bool     `json:"notSelectable,omitempty"`
+	NotFilterable   bool     `json:"notFilterable,omitempty"`
+	ExclusiveFields []string `json:"exclusiveFields,omitempty"`
+}
+
+// reportDef represents a report definition.
+// DateRanges lists the predefined date ranges supported by the report, all of them if empty.
+// MaxDays limits the number of days of a custom date range, without limit if zero.
+type reportDef struct {
+	Name       string   `json:"name"`
+	Fields     []*field `json:"fields"`
+	DateRanges []string `json:"dateRanges,omitempty"`
+	MaxDays    int      `json:"maxDays,omitempty"`
+	fields     map[string]*field
+}
+
+// field returns the field of the report with this name.
+func (r *reportDef) field(name string) (*field, bool) {
+	f, ok := r.fields[name]
+	return f, ok
+}
+
+// catalog lists by API version the report definitions.
+var catalog struct {
+	once    sync.Once
+	reports map[string]map[string]*reportDef
+}
+
+// loadCatalog parses the report definitions embedded for each API version.
+func loadCatalog() {
+	catalog.reports = make(map[string]map[string]*reportDef)
+
+	fs, err := reports.ReadDir("reports")
+	if err != nil {
+		return
+	}
+	for _, f := range fs {
+		b, err := reports.ReadFile("reports/" + f.Name())
+		if err != nil {
+			continue
+		}
+		var v struct {
+			Version string       `json:"version"`
+			Reports []*reportDef `json:"reports"`
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			continue
+		}
+		rs := make(map[string]*reportDef, len(v.Reports))
+		for _, r := range v.Reports {
+			r.fields = make(map[string]*field, len(r.Fields))
+			for _, f := range r.Fields {
+				r.fields[f.Name] = f
+			}
+			rs[r.Name] = r
+		}
+		catalog.reports[v.Version] = rs
+	}
+}
+
+// lookupReport returns the definition of the report for this API version.
+func lookupReport(version, name string) (*reportDef, bool) {
+	catalog.once.Do(loadCatalog)
+	r, ok := catalog.reports[version][strings.ToUpper(name)]
+	return r, ok
+}
+
+// lookupFields returns the definition of each field of the report for this API version.
+// A nil value is used for any unknown field.
+func lookupFields(version, report string, names []string) []*field {
+	r, ok := lookupReport(version, report)
+	if !ok {
+		return nil
+	}
+	fs := make([]*field, len(names))
+	for k, n := range names {
+		fs[k], _ = r.field(n)
+	}
+	return fs
+}
+
+// DatabaseTypeName returns the upper case name of the field type.
+func (f *field) DatabaseTypeName() string {
+	return strings.ToUpper(f.Type)
+}
+
+// Nullable returns true if the values of the field can be missing.
+// Only the counters and amounts of the metrics are always filled.
+func (f *field) Nullable() bool {
+	if f.Behavior != behaviorMetric {
+		return true
+	}
+	switch f.Type {
+	case typeInteger, typeLong, typeMoney:
+		return false
+	}
+	return true
+}
+
+// ScanType returns the Go type of the values of the field.
+// Money values are expressed in micros of the account currency.
+func (f *field) ScanType() reflect.Type {
+	switch f.Type {
+	case typeBoolean:
+		return reflect.TypeOf(false)
+	case typeDate:
+		return reflect.TypeOf(time.Time{})
+	case typeDouble:
+		return reflect.TypeOf(float64(0))
+	case typeInteger, typeLong, typeMoney:
+		return reflect.TypeOf(int64(0))
+	}
+	return reflect.TypeOf("")
+}
+
+// Value converts the report value into a value of the field type.
+// Missing values, represented by "--", are converted to nil.
+// Percentages like "12.34%" or "< 10%" are returned in percent, as 12.34 or 10.
+// If the value can not be converted, it is returned as it.
+func (f *field) Value(s string) driver.Value {
+	v := strings.TrimSpace(s)
+	if v == nullValue {
+		return nil
+	}
+	var (
+		rv  driver.Value
+		err error
+	)
+	switch f.Type {
+	case typeBoolean:
+		rv, err = strconv.ParseBool(v)
+	case typeDate:
+		rv, err = time.Parse(dateLayout, v)
+	case typeDouble:
+		v = strings.TrimSpace(strings.TrimLeft(strings.TrimSuffix(v, "%"), "<>"))
+		rv, err = strconv.ParseFloat(v, 64)
+	case typeInteger, typeLong, typeMoney:
+		rv, err = strconv.ParseInt(v, 10, 64)
+	default:
+		return s
+	}
+	if err != nil {
+		return s
+	}
+	return rv
+}
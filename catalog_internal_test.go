@@ -0,0 +1,107 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestLookupReport tests the report definitions embedded in the catalog.
+func TestLookupReport(t *testing.T) {
+	r, ok := lookupReport(APIVersion, "campaign_performance_report")
+	if !ok {
+		t.Fatalf("Expected the definition of the CAMPAIGN_PERFORMANCE_REPORT in %s", APIVersion)
+	}
+	if _, ok := r.field("Cost"); !ok {
+		t.Errorf("Expected the field Cost in %s", r.Name)
+	}
+	if _, ok := lookupReport("v201607", r.Name); ok {
+		t.Errorf("Expected no definition for an unknown version")
+	}
+	for _, rs := range catalog.reports {
+		for _, r := range rs {
+			if len(r.fields) != len(r.Fields) {
+				t.Errorf("Expected unique field names in %s", r.Name)
+			}
+			for _, f := range r.Fields {
+				switch f.Type {
+				case typeBoolean, typeDate, typeDouble, typeEnum, typeInteger, typeList, typeLong, typeMoney, typeString:
+				default:
+					t.Errorf("Unexpected type %s for the field %s of %s", f.Type, f.Name, r.Name)
+				}
+				switch f.Behavior {
+				case behaviorAttribute, behaviorMetric, behaviorSegment:
+				default:
+					t.Errorf("Unexpected behavior %s for the field %s of %s", f.Behavior, f.Name, r.Name)
+				}
+			}
+		}
+	}
+}
+
+// TestLookupFields tests the function named lookupFields.
+func TestLookupFields(t *testing.T) {
+	fs := lookupFields(APIVersion, "CAMPAIGN_PERFORMANCE_REPORT", []string{"CampaignId", "Oops"})
+	if len(fs) != 2 || fs[0] == nil || fs[0].Name != "CampaignId" || fs[1] != nil {
+		t.Errorf("Expected the field CampaignId and an unknown field, received %v", fs)
+	}
+	if fs := lookupFields(APIVersion, "OOPS_REPORT", []string{"CampaignId"}); fs != nil {
+		t.Errorf("Expected no field for an unknown report, received %v", fs)
+	}
+}
+
+// TestField_Value tests the method named Value of the field struct.
+func TestField_Value(t *testing.T) {
+	var fieldTests = []struct {
+		f   *field
+		in  string
+		out driver.Value
+	}{
+		{f: &field{Type: typeLong}, in: "19", out: int64(19)},
+		{f: &field{Type: typeMoney}, in: "1230000", out: int64(1230000)},
+		{f: &field{Type: typeInteger}, in: " --", out: nil},
+		{f: &field{Type: typeDouble}, in: "1.5", out: 1.5},
+		{f: &field{Type: typeDouble}, in: "12.34%", out: 12.34},
+		{f: &field{Type: typeDouble}, in: "< 10%", out: float64(10)},
+		{f: &field{Type: typeDouble}, in: "--", out: nil},
+		{f: &field{Type: typeDate}, in: "2017-01-31", out: time.Date(2017, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{f: &field{Type: typeBoolean}, in: "true", out: true},
+		{f: &field{Type: typeString}, in: "rv", out: "rv"},
+		{f: &field{Type: typeEnum}, in: "--", out: nil},
+		{f: &field{Type: typeLong}, in: "auto", out: "auto"},
+	}
+	for i, ft := range fieldTests {
+		if v := ft.f.Value(ft.in); !reflect.DeepEqual(v, ft.out) {
+			t.Errorf("%d. Expected %v (%T) with %q, received %v (%T)", i, ft.out, ft.out, ft.in, v, v)
+		}
+	}
+}
+
+// TestField_ColumnType tests the methods describing the column type of the field struct.
+func TestField_ColumnType(t *testing.T) {
+	var fieldTests = []struct {
+		f        *field
+		name     string
+		scan     reflect.Type
+		nullable bool
+	}{
+		{&field{Type: typeLong, Behavior: behaviorMetric}, "LONG", reflect.TypeOf(int64(0)), false},
+		{&field{Type: typeMoney, Behavior: behaviorAttribute}, "MONEY", reflect.TypeOf(int64(0)), true},
+		{&field{Type: typeDouble, Behavior: behaviorMetric}, "DOUBLE", reflect.TypeOf(float64(0)), true},
+		{&field{Type: typeDate, Behavior: behaviorSegment}, "DATE", reflect.TypeOf(time.Time{}), true},
+		{&field{Type: typeBoolean, Behavior: behaviorAttribute}, "BOOLEAN", reflect.TypeOf(false), true},
+		{&field{Type: typeEnum, Behavior: behaviorSegment}, "ENUM", reflect.TypeOf(""), true},
+	}
+	for i, ft := range fieldTests {
+		if n := ft.f.DatabaseTypeName(); n != ft.name {
+			t.Errorf("%d. Expected %s as type name, received %s", i, ft.name, n)
+		}
+		if s := ft.f.ScanType(); s != ft.scan {
+			t.Errorf("%d. Expected %v as scan type, received %v", i, ft.scan, s)
+		}
+		if n := ft.f.Nullable(); n != ft.nullable {
+			t.Errorf("%d. Expected %v as nullable, received %v", i, ft.nullable, n)
+		}
+	}
+}
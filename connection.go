@@ -1,29 +1,33 @@
 package awql
 
 import (
+	"context"
 	"database/sql/driver"
-	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
-	"net/url"
-	"strings"
-	"time"
-)
 
-const (
-	tokenURL            = "https://accounts.google.com/o/oauth2/token"
-	tokenTimeout        = time.Duration(4 * time.Second)
-	tokenExpiryDelta    = 10 * time.Second
-	tokenExpiryDuration = 60 * time.Minute
+	"github.com/rvflash/awql-driver/parser"
 )
 
+// pingQuery is the query of the report downloaded to check the access to the customer account.
+const pingQuery = "SELECT ExternalCustomerId FROM ACCOUNT_PERFORMANCE_REPORT DURING TODAY"
+
 // Conn represents a connection to a database and implements driver.Conn.
 type Conn struct {
 	client         *http.Client
 	adwordsID      string
 	developerToken string
-	oAuth          *Auth
+	tokens         TokenSource
 	opts           *Opts
+	cache          *Cache
+	cacheMode      CacheMode
+	customerIDs    []string
+	managerID      string
+	concurrency    int
+	retry          RetryPolicy
+	noValidate     bool
+	badAuth        bool
 }
 
 // Close marks this connection as no longer in use.
@@ -33,6 +37,70 @@ func (c *Conn) Close() error {
 	return nil
 }
 
+// IsValid implements the driver.Validator interface.
+// It returns false once the connection is closed or its credentials have been rejected,
+// to discard it from the connection pool.
+func (c *Conn) IsValid() bool {
+	return !c.closed() && !c.badAuth
+}
+
+// ResetSession implements the driver.SessionResetter interface.
+// It returns driver.ErrBadConn if the connection is not valid anymore,
+// so that the connection pool discards it and dials a new one.
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if !c.IsValid() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// Ping implements the driver.Pinger interface.
+// It retrieves a new access token and downloads the account performance report of the day
+// of the customer account, or of the first client account of the manager account,
+// to check that the credentials and the developer token are authorized to access it.
+// It returns driver.ErrBadConn if the connection is closed, otherwise the error of the API,
+// to check with errors.Is, as ErrAPIAuth when the access is denied.
+func (c *Conn) Ping(ctx context.Context) error {
+	if c.closed() {
+		return driver.ErrBadConn
+	}
+	if c.tokens != nil {
+		if _, err := refreshToken(ctx, c.tokens); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return c.checkErr(tokenError(err))
+		}
+	}
+	ids, err := c.customers(ctx)
+	if err != nil {
+		return c.checkErr(err)
+	}
+	o := *c.opts
+	o.SkipReportHeader, o.SkipReportSummary = true, true
+
+	s := &Stmt{Db: c, SrcQuery: pingQuery}
+	rc, err := s.download(ctx, ids[0], pingQuery, &o)
+	if err != nil {
+		return c.checkErr(err)
+	}
+	return rc.Close()
+}
+
+// closed returns true if the connection is closed.
+func (c *Conn) closed() bool {
+	return c.client == nil
+}
+
+// checkErr returns the error of a query and invalidates the connection if its credentials are rejected,
+// by the token endpoint or by the Adwords API. A network failure or a server error keeps it valid.
+func (c *Conn) checkErr(err error) error {
+	if errors.Is(err, ErrAPIAuth) {
+		c.badAuth = true
+	}
+	return err
+}
+
 // Begin is dedicated to start a transaction and awql does not support it.
 func (c *Conn) Begin() (driver.Tx, error) {
 	return nil, driver.ErrSkip
@@ -40,94 +108,72 @@ func (c *Conn) Begin() (driver.Tx, error) {
 
 // Prepare returns a prepared statement, bound to this connection.
 func (c *Conn) Prepare(q string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), q)
+}
+
+// PrepareContext returns a prepared statement, bound to this connection.
+// It returns a QueryError if the query is not a valid AWQL query, if its date range is not supported
+// by its report or, unless disabled with WithValidation, if its fields are not valid for a report of the catalog.
+// The context is only used during the preparation of the statement.
+func (c *Conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error) {
 	if q == "" {
 		// No query to prepare.
 		return nil, io.EOF
 	}
-	return &Stmt{Db: c, SrcQuery: q}, nil
-}
-
-// Auth returns an error if it can not download or parse the Google access token.
-func (c *Conn) authenticate() error {
-	if c.oAuth == nil || c.oAuth.Valid() {
-		// Authentication is not required or already validated.
-		return nil
+	s := &Stmt{Db: c, SrcQuery: q}
+	st, err := s.parse()
+	if err != nil {
+		return nil, err
 	}
-	if !c.oAuth.IsSet() {
-		// No client information to refresh the token.
-		return ErrBadToken
+	sel, ok := st.(*parser.SelectStmt)
+	if !ok {
+		return s, nil
 	}
-	d, err := c.downloadToken()
-	if err != nil {
-		return err
+	if err := checkDuring(q, s.version(), sel); err != nil {
+		return nil, err
 	}
-	return c.retrieveToken(d)
+	if !c.noValidate {
+		if err := validate(q, s.version(), sel); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
-// downloadToken calls Google Auth Api to retrieve an access token.
-// @example Google Token
-// {
-//     "access_token": "ya29.ExaMple",
-//     "token_type": "Bearer",
-//     "expires_in": 60
-// }
-func (c *Conn) downloadToken() (io.ReadCloser, error) {
-	rq, err := http.NewRequest(
-		"POST", tokenURL,
-		strings.NewReader(url.Values{
-			"client_id":     {c.oAuth.ClientID},
-			"client_secret": {c.oAuth.ClientSecret},
-			"refresh_token": {c.oAuth.RefreshToken},
-			"grant_type":    {"refresh_token"},
-		}.Encode()),
-	)
+// QueryContext prepares the query, binds the given arguments and executes it.
+// The context cancels the download of the report and bounds its duration.
+func (c *Conn) QueryContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	s, err := c.PrepareContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
-	c.client.Timeout = tokenTimeout
-	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.(*Stmt).QueryContext(ctx, args)
+}
 
-	// Retrieves an access token
-	resp, err := c.client.Do(rq)
+// CheckNamedValue accepts the slices as arguments, bound as list of values, as with the IN operator,
+// and the date ranges of the DURING clause.
+// Any other value is converted by the default converter of the database/sql package.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+// authorization returns the value of the Authorization header with an access token of the token source.
+func (c *Conn) authorization(ctx context.Context) (string, error) {
+	tk, err := c.tokens.Token(ctx)
 	if err != nil {
-		return nil, err
-	}
-	// Manages response in error
-	if resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case 0:
-			return nil, ErrNoNetwork
-		case http.StatusBadRequest:
-			return nil, ErrBadToken
-		default:
-			return nil, ErrBadNetwork
+		if ctx.Err() != nil {
+			// Cancellation or deadline of the caller.
+			return "", ctx.Err()
 		}
+		return "", tokenError(err)
 	}
-	return resp.Body, nil
+	return tk.String(), nil
 }
 
-// retrieveToken parses the JSON response in order to map it to a AuthToken.
-// An error occurs if the JSON is invalid.
-func (c *Conn) retrieveToken(d io.ReadCloser) error {
-	var tk struct {
-		AccessToken  string `json:"access_token"`
-		ExpiresInSec int    `json:"expires_in"`
-		TokenType    string `json:"token_type"`
-	}
-	defer d.Close()
-
-	err := json.NewDecoder(d).Decode(&tk)
-	if err != nil {
-		// Unable to parse the JSON response.
-		return ErrBadToken
-	}
-	if tk.ExpiresInSec == 0 || tk.AccessToken == "" {
-		// Invalid format of the token.
-		return ErrBadToken
+// tokenError returns the failure of the token source as ErrBadToken, wrapping its cause.
+func tokenError(err error) error {
+	if errors.Is(err, ErrBadToken) {
+		return err
 	}
-	c.oAuth.AccessToken = tk.AccessToken
-	c.oAuth.TokenType = tk.TokenType
-	c.oAuth.Expiry = time.Now().Add(time.Duration(tk.ExpiresInSec) * time.Second)
-
-	return nil
+	return wrapConnectionError(ErrBadToken, err)
 }
@@ -1,7 +1,9 @@
 package awql
 
 import (
+	"context"
 	"database/sql/driver"
+	"errors"
 	"io"
 	"net/http"
 	"testing"
@@ -43,4 +45,66 @@ func TestAwqlConn_Prepare(t *testing.T) {
 			t.Errorf("Expected driver.ErrSkip when we begin a transaction, received %v", err)
 		}
 	}
+	c := &Conn{client: http.DefaultClient}
+	_, err := c.Prepare("SELECT AccountDescriptiveName FORM ACCOUNT_PERFORMANCE_REPORT")
+	if err == nil || err.Error() != "QueryError.UNEXPECTED_TOKEN (FORM) at position 31" {
+		t.Errorf("Expected a query error on the invalid token, received %v", err)
+	}
+}
+
+// TestAwqlConn_QueryContext tests the method named QueryContext on Conn struct.
+func TestAwqlConn_QueryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &Conn{client: http.DefaultClient, opts: NewOpts("", false, false, false)}
+	if _, err := c.QueryContext(ctx, "", nil); err != io.EOF {
+		t.Errorf("Expected io.EOF with an empty query, received %v", err)
+	}
+	q := "SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT"
+	if _, err := c.QueryContext(ctx, q, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled with a canceled context, received %v", err)
+	}
+}
+
+// TestAwqlConn_IsValid tests the methods named IsValid and ResetSession on Conn struct.
+func TestAwqlConn_IsValid(t *testing.T) {
+	var validTests = []struct {
+		conn *Conn
+		err  error
+		ok   bool
+	}{
+		{conn: &Conn{client: http.DefaultClient}, ok: true},
+		{conn: &Conn{client: http.DefaultClient}, err: ErrBadNetwork, ok: true},
+		{conn: &Conn{client: http.DefaultClient}, err: &APIError{Type: "QueryError.INVALID_VALUE"}, ok: true},
+		{conn: &Conn{client: http.DefaultClient}, err: &APIError{Type: "AuthenticationError.OAUTH_TOKEN_REVOKED"}},
+		{conn: &Conn{client: http.DefaultClient}, err: &APIError{StatusCode: http.StatusUnauthorized}},
+		{conn: &Conn{client: http.DefaultClient}, err: wrapConnectionError(ErrBadToken, &oauthError{Code: "invalid_grant"})},
+		{conn: &Conn{client: http.DefaultClient}, err: wrapConnectionError(ErrBadToken, ErrBadNetwork), ok: true},
+		{conn: &Conn{client: http.DefaultClient}, err: ErrBadToken, ok: true},
+		{conn: &Conn{}},
+	}
+	for i, vt := range validTests {
+		if err := vt.conn.checkErr(vt.err); err != vt.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, vt.err, err)
+		}
+		if ok := vt.conn.IsValid(); ok != vt.ok {
+			t.Errorf("%d. Expected %t as validity, received %t", i, vt.ok, ok)
+		}
+		err := vt.conn.ResetSession(context.Background())
+		if (err == nil) != vt.ok || (err != nil && err != driver.ErrBadConn) {
+			t.Errorf("%d. Expected driver.ErrBadConn only if invalid, received %v", i, err)
+		}
+	}
+}
+
+// TestAwqlConn_QueryContext_Closed tests the query on a closed connection.
+func TestAwqlConn_QueryContext_Closed(t *testing.T) {
+	c := &Conn{client: http.DefaultClient, opts: NewOpts("", false, false, false)}
+	_ = c.Close()
+
+	q := "SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT"
+	if _, err := c.QueryContext(context.Background(), q, nil); err != driver.ErrBadConn {
+		t.Errorf("Expected driver.ErrBadConn on a closed connection, received %v", err)
+	}
 }
@@ -0,0 +1,220 @@
+package awql
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+)
+
+// Connector represents a driver in a fixed configuration and implements driver.Connector.
+// Used with sql.OpenDB, it avoids to encode the secrets in a DSN string.
+type Connector struct {
+	dsn    Dsn
+	client *http.Client
+	auth   *Auth
+	tokens TokenSource
+	store  TokenStore
+	opts   *Opts
+	cache  *Cache
+	mode   CacheMode
+
+	customerIDs []string
+	managerID   string
+	concurrency int
+	retry       RetryPolicy
+	noValidate  bool
+}
+
+// ConnectorOption represents an option to configure a Connector.
+type ConnectorOption func(c *Connector)
+
+// WithHTTPClient uses the given HTTP client to call the Google APIs.
+// By default, http.DefaultClient is used.
+func WithHTTPClient(client *http.Client) ConnectorOption {
+	return func(c *Connector) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithAuth uses the given authentication instead of the credentials of the data source name.
+// The connector keeps its own copy: the later changes of the given authentication are ignored.
+func WithAuth(a *Auth) ConnectorOption {
+	return func(c *Connector) {
+		if a == nil {
+			c.auth = nil
+			return
+		}
+		cp := *a
+		c.auth = &cp
+	}
+}
+
+// WithTokenSource uses the given source of access tokens instead of the credentials
+// of the data source name. The token source is shared by all the connections.
+func WithTokenSource(ts TokenSource) ConnectorOption {
+	return func(c *Connector) {
+		c.tokens = ts
+	}
+}
+
+// WithTokenStore persists in the given store the access tokens retrieved with
+// a refresh token or a service account, in order to reuse them after a restart.
+// See NewFileTokenStore.
+func WithTokenStore(store TokenStore) ConnectorOption {
+	return func(c *Connector) {
+		c.store = store
+	}
+}
+
+// WithOpts uses the given Adwords API options instead of those of the data source name.
+func WithOpts(o *Opts) ConnectorOption {
+	return func(c *Connector) {
+		c.opts = o
+	}
+}
+
+// WithCache stores the downloaded reports in the given cache.
+// By default, the reports are not cached.
+func WithCache(cache *Cache) ConnectorOption {
+	return func(c *Connector) {
+		c.cache = cache
+	}
+}
+
+// WithCacheMode defines how the queries use the cache, CacheUse by default.
+// ContextWithCacheMode can be used to override it for a query.
+func WithCacheMode(m CacheMode) ConnectorOption {
+	return func(c *Connector) {
+		c.mode = m
+	}
+}
+
+// WithCustomerIDs runs the queries on each of the given customer accounts
+// instead of the one of the data source name.
+func WithCustomerIDs(ids ...string) ConnectorOption {
+	return func(c *Connector) {
+		c.customerIDs = ids
+	}
+}
+
+// WithManagerID runs the queries on each client account of the given manager account.
+// The client accounts are listed with the ManagedCustomerService of the Adwords API.
+func WithManagerID(id string) ConnectorOption {
+	return func(c *Connector) {
+		c.managerID = id
+	}
+}
+
+// WithConcurrency limits the number of reports downloaded in parallel
+// when the queries run on multiple customer accounts, 5 by default.
+func WithConcurrency(n int) ConnectorOption {
+	return func(c *Connector) {
+		c.concurrency = n
+	}
+}
+
+// WithValidation enables or disables the validation of the queries against the catalog of the reports
+// when they are prepared. Enabled by default. As the catalog only lists the main fields of the main reports,
+// a field missing from it is only rejected if it differs in case from a field of the report.
+func WithValidation(enabled bool) ConnectorOption {
+	return func(c *Connector) {
+		c.noValidate = !enabled
+	}
+}
+
+// WithRetryPolicy retries the requests failing with a transient error with the given policy
+// instead of DefaultRetryPolicy. Its zero properties are those of DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) ConnectorOption {
+	return func(c *Connector) {
+		c.retry = p.withDefaults()
+	}
+}
+
+// NewConnector returns a new instance of Connector based on the data source name
+// and configured with the given options, prevailing over the data source name.
+// The source of the access tokens is built once, shared by all the connections:
+// it returns an error if the credentials can not be used, as an invalid service account key.
+func NewConnector(dsn *Dsn, opts ...ConnectorOption) (*Connector, error) {
+	c := &Connector{client: http.DefaultClient, retry: DefaultRetryPolicy}
+	if dsn != nil {
+		c.dsn = *dsn
+		c.managerID = dsn.ManagerID
+		c.concurrency = dsn.Concurrency
+		c.retry = dsn.Retry.withDefaults()
+		c.mode = dsn.CacheMode
+		c.noValidate = dsn.SkipValidation
+		if dsn.TokenFile != "" {
+			c.store = NewFileTokenStore(dsn.TokenFile)
+		}
+		if dsn.CacheDir != "" {
+			c.cache = NewCache(dsn.CacheDir, dsn.CacheTTL, dsn.CacheMaxSize)
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	switch {
+	case c.tokens != nil:
+		// Given by WithTokenSource.
+	case c.auth != nil:
+		c.tokens = c.auth.tokenSource(c.client, c.retry, c.store)
+	default:
+		var err error
+		if c.tokens, err = c.dsn.tokenSource(c.client, c.retry, c.store); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Connect returns a new connection to the database.
+// The authentication is delayed until the first request in order to report its errors.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn := &Conn{
+		client:         c.client,
+		developerToken: c.dsn.DeveloperToken,
+		cache:          c.cache,
+		cacheMode:      c.mode,
+		concurrency:    c.concurrency,
+		retry:          c.retry,
+		noValidate:     c.noValidate,
+	}
+	switch {
+	case len(c.customerIDs) > 0:
+		conn.customerIDs = c.customerIDs
+	case c.managerID != "":
+		conn.managerID = c.managerID
+	default:
+		conn.customerIDs = customerIDs(c.dsn.AdwordsID)
+	}
+	if len(conn.customerIDs) > 0 {
+		conn.adwordsID = conn.customerIDs[0]
+	} else {
+		conn.adwordsID = conn.managerID
+	}
+	if conn.adwordsID == "" {
+		return nil, ErrAdwordsID
+	}
+	if conn.developerToken == "" {
+		return nil, ErrDevToken
+	}
+	if c.opts != nil {
+		o := *c.opts
+		conn.opts = &o
+	} else {
+		conn.opts = NewOpts(
+			c.dsn.APIVersion, c.dsn.SupportsZeroImpressions, c.dsn.SkipColumnHeader, c.dsn.UseRawEnumValues,
+		)
+		conn.opts.SkipReportHeader = !c.dsn.IncludeReportHeader
+		conn.opts.SkipReportSummary = !c.dsn.IncludeReportSummary
+	}
+	conn.tokens = c.tokens
+	return conn, nil
+}
+
+// Driver returns the underlying driver of the connector.
+func (c *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
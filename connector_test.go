@@ -0,0 +1,375 @@
+package awql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// roundTripFunc mocks the Google APIs by implementing http.RoundTripper.
+type roundTripFunc func(rq *http.Request) (*http.Response, error)
+
+// RoundTrip implements the http.RoundTripper interface.
+func (f roundTripFunc) RoundTrip(rq *http.Request) (*http.Response, error) {
+	return f(rq)
+}
+
+// newClient returns a HTTP client responding to any request with the given status and body.
+func newClient(status int, body string, rqs *[]*http.Request) *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			if rqs != nil {
+				*rqs = append(*rqs, rq)
+			}
+			return &http.Response{
+				StatusCode: status,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    rq,
+			}, nil
+		}),
+	}
+}
+
+// newConnector returns a new connector or fails the test.
+func newConnector(t *testing.T, dsn *awql.Dsn, opts ...awql.ConnectorOption) *awql.Connector {
+	t.Helper()
+	c, err := awql.NewConnector(dsn, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error with the connector, received %v", err)
+	}
+	return c
+}
+
+// TestConnector_Connect tests the method Connect on Connector struct.
+func TestConnector_Connect(t *testing.T) {
+	var connectorTests = []struct {
+		dsn  *awql.Dsn
+		opts []awql.ConnectorOption
+		err  error
+	}{
+		{dsn: nil, err: awql.ErrAdwordsID},
+		{dsn: &awql.Dsn{AdwordsID: "123-456-7890"}, err: awql.ErrDevToken},
+		{dsn: &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", ClientID: "c1i3n7iD"}, err: awql.ErrBadToken},
+		{dsn: &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}},
+		{dsn: &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", AccessToken: "ya29.AcC3s57okeN"}},
+		{
+			dsn:  &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", ClientID: "c1i3n7iD"},
+			opts: []awql.ConnectorOption{awql.WithAuth(&awql.Auth{})},
+		},
+	}
+	for i, ct := range connectorTests {
+		c, err := awql.NewConnector(ct.dsn, ct.opts...)
+		if err == nil {
+			_, err = c.Connect(context.Background())
+		}
+		if err != ct.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, ct.err, err)
+		}
+		if c == nil {
+			continue
+		}
+		if _, ok := c.Driver().(*awql.Driver); !ok {
+			t.Errorf("%d. Expected the awql driver, received %T", i, c.Driver())
+		}
+	}
+}
+
+// TestOpenDB tests the usage of a Connector with sql.OpenDB.
+func TestOpenDB(t *testing.T) {
+	var rqs []*http.Request
+	dsn := awql.NewDsn("123-456-7890")
+	dsn.DeveloperToken = "dEve1op3er7okeN"
+	auth, _ := awql.NewAuthByToken("ya29.AcC3s57okeN")
+	exp := auth.String()
+	db := sql.OpenDB(newConnector(
+		t, dsn,
+		awql.WithHTTPClient(newClient(http.StatusOK, "Account\nRv\n", &rqs)),
+		awql.WithAuth(auth),
+		awql.WithOpts(awql.NewOpts("v201710", true, false, false)),
+	))
+	defer db.Close()
+
+	// The connector uses its own copy of the authentication.
+	auth.AccessToken = "ya29.Ch4ng3d"
+
+	var name string
+	err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if name != "Rv" {
+		t.Errorf("Expected Rv as account name, received %v", name)
+	}
+	if len(rqs) != 1 {
+		t.Fatalf("Expected one request with the injected client, received %d", len(rqs))
+	}
+	if u := rqs[0].URL.String(); !strings.HasSuffix(u, "v201710") {
+		t.Errorf("Expected the API version of the options, received %v", u)
+	}
+	if h := rqs[0].Header.Get("Authorization"); h != exp {
+		t.Errorf("Expected the injected access token, received %v", h)
+	}
+	if h := rqs[0].Header.Get("includeZeroImpressions"); h != "true" {
+		t.Errorf("Expected the zero impressions of the options, received %v", h)
+	}
+}
+
+// TestWithRetryPolicy tests the retries of the report download.
+func TestWithRetryPolicy(t *testing.T) {
+	var (
+		n    int
+		resp = []struct {
+			status int
+			body   string
+		}{
+			{status: http.StatusServiceUnavailable},
+			{status: http.StatusBadRequest, body: "<reportDownloadError><ApiError><type>RateExceededError.RATE_EXCEEDED</type></ApiError></reportDownloadError>"},
+			{status: http.StatusOK, body: "Account\nRv\n"},
+		}
+	)
+	client := &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			r := resp[n]
+			n++
+			return &http.Response{
+				StatusCode: r.status,
+				Header:     http.Header{"Retry-After": {"0"}},
+				Body:       ioutil.NopCloser(strings.NewReader(r.body)),
+				Request:    rq,
+			}, nil
+		}),
+	}
+	var retryTests = []struct {
+		policy   awql.RetryPolicy
+		attempts int
+		err      string
+	}{
+		{policy: awql.RetryPolicy{MaxAttempts: 1}, attempts: 1, err: "503 Service Unavailable"},
+		{policy: awql.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}, attempts: 2, err: "RateExceededError.RATE_EXCEEDED"},
+		{policy: awql.RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond}, attempts: 3},
+	}
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	for i, rt := range retryTests {
+		n = 0
+		db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(client), awql.WithRetryPolicy(rt.policy)))
+
+		var name string
+		err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name)
+		switch {
+		case rt.err == "" && err != nil:
+			t.Errorf("%d. Expected no error, received %v", i, err)
+		case rt.err != "" && (err == nil || err.Error() != rt.err):
+			t.Errorf("%d. Expected %s as error, received %v", i, rt.err, err)
+		}
+		if n != rt.attempts {
+			t.Errorf("%d. Expected %d attempts, received %d", i, rt.attempts, n)
+		}
+		_ = db.Close()
+	}
+}
+
+// TestWithValidation tests the validation of the queries when they are prepared.
+func TestWithValidation(t *testing.T) {
+	const q = "SELECT CampaignName, clicks FROM CAMPAIGN_PERFORMANCE_REPORT"
+	var rqs []*http.Request
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(newClient(http.StatusOK, "Campaign,Clicks\n", &rqs))))
+	defer db.Close()
+
+	if _, err := db.Prepare(q); err == nil || err.Error() != "QueryError.UNKNOWN_FIELD (clicks) at position 22" {
+		t.Errorf("Expected an unknown field error, received %v", err)
+	}
+	if len(rqs) != 0 {
+		t.Errorf("Expected no request, received %d", len(rqs))
+	}
+
+	// Accepts the fields missing from the catalog.
+	if _, err := db.Prepare("SELECT CampaignName, CampaignGroupId FROM CAMPAIGN_PERFORMANCE_REPORT"); err != nil {
+		t.Errorf("Expected no error with a field missing from the catalog, received %v", err)
+	}
+
+	// Disabled validation.
+	db = sql.OpenDB(newConnector(
+		t, dsn, awql.WithHTTPClient(newClient(http.StatusOK, "Campaign,Clicks\n", &rqs)), awql.WithValidation(false),
+	))
+	defer db.Close()
+
+	if _, err := db.Prepare(q); err != nil {
+		t.Errorf("Expected no error without validation, received %v", err)
+	}
+
+	// Disabled by the data source name.
+	vdsn := *dsn
+	vdsn.SkipValidation = true
+	db = sql.OpenDB(newConnector(t, &vdsn, awql.WithHTTPClient(newClient(http.StatusOK, "Campaign,Clicks\n", &rqs))))
+	defer db.Close()
+
+	if _, err := db.Prepare(q); err != nil {
+		t.Errorf("Expected no error without validation of the data source name, received %v", err)
+	}
+}
+
+// TestConnector_Connect_BadAuth tests the eviction of the connections with rejected credentials.
+func TestConnector_Connect_BadAuth(t *testing.T) {
+	const body = "<reportDownloadError><ApiError><type>AuthenticationError.OAUTH_TOKEN_REVOKED</type>" +
+		"<trigger></trigger><fieldPath></fieldPath></ApiError></reportDownloadError>"
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(newClient(http.StatusBadRequest, body, nil))))
+	defer db.Close()
+
+	_, err := db.Query("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if !errors.Is(err, awql.ErrAPIAuth) {
+		t.Fatalf("Expected an authentication error, received %v", err)
+	}
+	if st := db.Stats(); st.OpenConnections != 0 || st.Idle != 0 {
+		t.Errorf("Expected the connection discarded, received %d open connections", st.OpenConnections)
+	}
+
+	// Keeps the connection on any other error.
+	body2 := strings.Replace(body, "AuthenticationError.OAUTH_TOKEN_REVOKED", "QueryError.INVALID_VALUE", 1)
+	db = sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(newClient(http.StatusBadRequest, body2, nil))))
+	defer db.Close()
+
+	if _, err = db.Query("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT"); !errors.Is(err, awql.ErrAPIQuery) {
+		t.Fatalf("Expected a query error, received %v", err)
+	}
+	if st := db.Stats(); st.Idle != 1 {
+		t.Errorf("Expected the connection kept in the pool, received %d idle connections", st.Idle)
+	}
+}
+
+// TestConnector_Connect_TokenFailure tests the connections failing to retrieve an access token.
+// Only the credentials rejected by the token endpoint discard the connection.
+func TestConnector_Connect_TokenFailure(t *testing.T) {
+	var tokenTests = []struct {
+		rt      roundTripFunc
+		err     error
+		evicted bool
+	}{
+		{
+			rt: func(rq *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset by peer")
+			},
+			err: awql.ErrBadNetwork,
+		},
+		{
+			rt: func(rq *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Header:     make(http.Header),
+					Body:       ioutil.NopCloser(strings.NewReader(`{"error":"invalid_grant","error_description":"Token has been revoked."}`)),
+					Request:    rq,
+				}, nil
+			},
+			err:     awql.ErrAPIAuth,
+			evicted: true,
+		},
+	}
+	for i, tt := range tokenTests {
+		dsn := &awql.Dsn{
+			AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN",
+			ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/T0k3nFa1lur3-" + strconv.Itoa(i),
+		}
+		db := sql.OpenDB(newConnector(
+			t, dsn, awql.WithHTTPClient(&http.Client{Transport: tt.rt}), awql.WithRetryPolicy(awql.RetryPolicy{MaxAttempts: 1}),
+		))
+		_, err := db.Query("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT")
+		if !errors.Is(err, awql.ErrBadToken) || !errors.Is(err, tt.err) {
+			t.Errorf("%d. Expected %v caused by %v, received %v", i, awql.ErrBadToken, tt.err, err)
+		}
+		if st := db.Stats(); (st.Idle == 0) != tt.evicted {
+			t.Errorf("%d. Expected the connection discarded: %t, received %d idle connections", i, tt.evicted, st.Idle)
+		}
+		_ = db.Close()
+	}
+}
+
+// TestConn_Ping tests the check of the access to the customer account with Ping.
+func TestConn_Ping(t *testing.T) {
+	const denied = "<reportDownloadError><ApiError><type>AuthorizationError.USER_PERMISSION_DENIED</type>" +
+		"<trigger></trigger><fieldPath></fieldPath></ApiError></reportDownloadError>"
+	var pingTests = []struct {
+		status int
+		body   string
+		err    error
+	}{
+		{status: http.StatusOK, body: "Customer ID\n1234567890\n"},
+		{status: http.StatusBadRequest, body: denied, err: awql.ErrAPIAuth},
+		{status: http.StatusServiceUnavailable, err: awql.ErrAPIInternal},
+	}
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	for i, pt := range pingTests {
+		var (
+			rqs []*http.Request
+			src = &countTokenSource{}
+		)
+		db := sql.OpenDB(newConnector(
+			t, dsn,
+			awql.WithHTTPClient(newClient(pt.status, pt.body, &rqs)),
+			awql.WithTokenSource(awql.ReuseTokenSource(nil, src)),
+			awql.WithRetryPolicy(awql.RetryPolicy{MaxAttempts: 1}),
+		))
+		for k := 0; k < 2; k++ {
+			if err := db.Ping(); !errors.Is(err, pt.err) {
+				t.Errorf("%d. Expected %v as error, received %v", i, pt.err, err)
+			}
+		}
+		if src.n != 2 {
+			t.Errorf("%d. Expected a new token on each ping, received %d", i, src.n)
+		}
+		if len(rqs) == 0 {
+			t.Fatalf("%d. Expected a probe request", i)
+		}
+		_ = rqs[0].ParseForm()
+		if q := rqs[0].PostForm.Get("__rdquery"); !strings.HasPrefix(q, "SELECT ExternalCustomerId FROM ACCOUNT_PERFORMANCE_REPORT") {
+			t.Errorf("%d. Expected the probe report, received %s", i, q)
+		}
+		if id := rqs[0].Header.Get("clientCustomerId"); id != dsn.AdwordsID {
+			t.Errorf("%d. Expected %s as customer account, received %s", i, dsn.AdwordsID, id)
+		}
+		if open := db.Stats().OpenConnections; (open == 0) != (pt.err == awql.ErrAPIAuth) {
+			t.Errorf("%d. Expected the connection discarded only on authentication error, received %d", i, open)
+		}
+		_ = db.Close()
+	}
+
+	// Fails without a valid token, with the cause of the failure.
+	errOops := errors.New("oops")
+	db := sql.OpenDB(newConnector(
+		t, dsn,
+		awql.WithHTTPClient(newClient(http.StatusOK, "", nil)),
+		awql.WithTokenSource(&countTokenSource{err: errOops}),
+	))
+	defer db.Close()
+
+	if err := db.Ping(); !errors.Is(err, awql.ErrBadToken) || !errors.Is(err, errOops) {
+		t.Errorf("Expected %v caused by %v, received %v", awql.ErrBadToken, errOops, err)
+	}
+	if st := db.Stats(); st.Idle != 1 {
+		t.Errorf("Expected the connection kept in the pool, received %d idle connections", st.Idle)
+	}
+
+	// Discards the connection with rejected credentials.
+	key := awql.AuthKey{ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/R3v0k3d-70k3n"}
+	db = sql.OpenDB(newConnector(
+		t, dsn,
+		awql.WithTokenSource(awql.RefreshTokenSource(newClient(http.StatusBadRequest, `{"error":"invalid_grant"}`, nil), key)),
+	))
+	defer db.Close()
+
+	if err := db.Ping(); !errors.Is(err, awql.ErrBadToken) || !errors.Is(err, awql.ErrAPIAuth) {
+		t.Errorf("Expected %v as authentication error, received %v", awql.ErrBadToken, err)
+	}
+	if st := db.Stats(); st.OpenConnections != 0 || st.Idle != 0 {
+		t.Errorf("Expected the connection discarded, received %d open connections", st.OpenConnections)
+	}
+}
@@ -0,0 +1,358 @@
+package awql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+const (
+	customerURL         = "https://adwords.google.com/api/adwords/mcm/%s/ManagedCustomerService"
+	customerPageSize    = 500
+	customerIDField     = "ExternalCustomerId"
+	defaultConcurrency  = 5
+	customerUserAgent   = "awql-driver"
+	customerNamespace   = "https://adwords.google.com/api/adwords/mcm/"
+	commonNamespace     = "https://adwords.google.com/api/adwords/cm/"
+	customerRequestBody = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+	<soapenv:Header>
+		<RequestHeader xmlns="%[1]s%[2]s">
+			<clientCustomerId xmlns="%[3]s%[2]s">%[4]s</clientCustomerId>
+			<developerToken xmlns="%[3]s%[2]s">%[5]s</developerToken>
+			<userAgent xmlns="%[3]s%[2]s">%[6]s</userAgent>
+		</RequestHeader>
+	</soapenv:Header>
+	<soapenv:Body>
+		<get xmlns="%[1]s%[2]s">
+			<serviceSelector>
+				<fields xmlns="%[3]s%[2]s">CustomerId</fields>
+				<fields xmlns="%[3]s%[2]s">CanManageClients</fields>
+				<paging xmlns="%[3]s%[2]s">
+					<startIndex>%[7]d</startIndex>
+					<numberResults>%[8]d</numberResults>
+				</paging>
+			</serviceSelector>
+		</get>
+	</soapenv:Body>
+</soapenv:Envelope>`
+)
+
+// customerIDs returns the list of the customer IDs separated by a comma.
+func customerIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, DsnIDSep) {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// fanOut returns true if the queries run on multiple customer accounts.
+func (c *Conn) fanOut() bool {
+	return len(c.customerIDs) > 1 || c.managerID != ""
+}
+
+// customers returns the IDs of the customer accounts on which the queries run.
+// With a manager account, its client accounts are only listed once by connection.
+func (c *Conn) customers(ctx context.Context) ([]string, error) {
+	if len(c.customerIDs) > 0 {
+		return c.customerIDs, nil
+	}
+	if c.managerID == "" {
+		return []string{c.adwordsID}, nil
+	}
+	ids, err := c.managedCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoCustomer
+	}
+	c.customerIDs = ids
+
+	return ids, nil
+}
+
+// managedCustomers calls the ManagedCustomerService of the Adwords API
+// to list the client accounts of the manager account, other managers excluded.
+// @see https://developers.google.com/adwords/api/docs/reference/v201809/ManagedCustomerService
+func (c *Conn) managedCustomers(ctx context.Context) ([]string, error) {
+	var ids []string
+	for start := 0; ; start += customerPageSize {
+		page, total, err := c.managedCustomersPage(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, page...)
+		if start+customerPageSize >= total {
+			return ids, nil
+		}
+	}
+}
+
+// managedCustomersPage returns the client accounts of the page starting at this index
+// and the total number of accounts managed by the manager account.
+// The requests failing with a transient error are retried with the retry policy of the connection.
+func (c *Conn) managedCustomersPage(ctx context.Context, start int) (ids []string, total int, err error) {
+	err = c.retry.do(ctx, func() (err error) {
+		ids, total, err = c.requestManagedCustomers(ctx, start)
+		return
+	})
+	return
+}
+
+// requestManagedCustomers requests the page of the client accounts starting at this index.
+func (c *Conn) requestManagedCustomers(ctx context.Context, start int) ([]string, int, error) {
+	v := c.opts.Version
+	rq, err := http.NewRequestWithContext(
+		ctx, "POST", fmt.Sprintf(customerURL, v),
+		strings.NewReader(fmt.Sprintf(
+			customerRequestBody, customerNamespace, v, commonNamespace,
+			xmlEscape(c.managerID), xmlEscape(c.developerToken), customerUserAgent, start, customerPageSize,
+		)),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	rq.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	rq.Header.Set("SOAPAction", "")
+
+	if c.tokens != nil {
+		a, err := c.authorization(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		rq.Header.Set("Authorization", a)
+	}
+	resp, err := c.client.Do(rq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, err
+		}
+		// Network failure.
+		return nil, 0, &retryError{err: wrapConnectionError(ErrBadNetwork, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 0 {
+		return nil, 0, ErrNoNetwork
+	}
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	var env struct {
+		Total   int `xml:"Body>getResponse>rval>totalNumEntries"`
+		Entries []struct {
+			CustomerID       int64 `xml:"customerId"`
+			CanManageClients bool  `xml:"canManageClients"`
+		} `xml:"Body>getResponse>rval>entries"`
+		RequestID string `xml:"Header>ResponseHeader>requestId"`
+		Fault     string `xml:"Body>Fault>faultstring"`
+		Errors    []struct {
+			Type    string `xml:"errorString"`
+			Trigger string `xml:"trigger"`
+			Field   string `xml:"fieldPath"`
+		} `xml:"Body>Fault>detail>ApiExceptionFault>errors"`
+	}
+	if err := xml.Unmarshal(d, &env); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			e := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get(requestIDHeader)}
+			return nil, 0, retryableResponse(e, resp)
+		}
+		return nil, 0, wrapConnectionError(ErrBadResponse, err)
+	}
+	switch {
+	case len(env.Errors) > 0:
+		e := &APIError{StatusCode: resp.StatusCode, RequestID: env.RequestID}
+		for _, d := range env.Errors {
+			e.Errors = append(e.Errors, APIErrorDetail(d))
+		}
+		e.Type, e.Trigger, e.Field = e.Errors[0].Type, e.Errors[0].Trigger, e.Errors[0].Field
+		return nil, 0, retryableResponse(e, resp)
+	case env.Fault != "":
+		e := &APIError{Type: env.Fault, StatusCode: resp.StatusCode, RequestID: env.RequestID}
+		return nil, 0, retryableResponse(e, resp)
+	case resp.StatusCode != http.StatusOK:
+		return nil, 0, retryableResponse(&APIError{StatusCode: resp.StatusCode, RequestID: env.RequestID}, resp)
+	}
+	var ids []string
+	for _, e := range env.Entries {
+		if !e.CanManageClients {
+			ids = append(ids, strconv.FormatInt(e.CustomerID, 10))
+		}
+	}
+	return ids, env.Total, nil
+}
+
+// xmlEscape returns the string escaped to be used as XML text.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// withCustomerID returns a copy of the statement selecting also the ExternalCustomerId field.
+func withCustomerID(st *parser.SelectStmt) *parser.SelectStmt {
+	c := *st
+	c.Fields = append(st.Fields[:len(st.Fields):len(st.Fields)], &parser.Column{
+		Field: &parser.Ident{Name: customerIDField},
+	})
+	return &c
+}
+
+// fanOutRows runs the query of the plan on each customer account and returns the concatenation
+// of their rows, in order of the accounts. The reports are streamed account by account,
+// with at most the concurrency of the connection downloaded ahead of the reading.
+// The columns and the header are those of the first account with columns.
+// The first error cancels the other queries.
+func (s *Stmt) fanOutRows(ctx context.Context, ids []string, st *parser.SelectStmt, p *plan) (*Rows, error) {
+	n := s.Db.concurrency
+	if n <= 0 {
+		n = defaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	src := &fanOutSource{
+		ctx:     ctx,
+		cancel:  cancel,
+		results: make([]chan fanOutResult, len(ids)),
+		sem:     make(chan struct{}, n),
+	}
+	for k := range ids {
+		src.results[k] = make(chan fanOutResult, 1)
+	}
+	src.wg.Add(1)
+	go func() {
+		defer src.wg.Done()
+		for k, id := range ids {
+			select {
+			case src.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			src.wg.Add(1)
+			go func(k int, id string) {
+				defer src.wg.Done()
+				rs, err := s.rows(ctx, id, st, p)
+				src.results[k] <- fanOutResult{rs: rs, err: err}
+			}(k, id)
+		}
+	}()
+	rs := &Rows{
+		cols:   []string{},
+		fields: lookupFields(s.Db.opts.Version, st.From.Name, p.fields),
+		src:    src,
+	}
+	// Waits for the columns of the first accounts.
+	for len(rs.cols) == 0 && src.pos < len(ids) {
+		if err := src.open(); err != nil {
+			_ = src.Close()
+			return nil, err
+		}
+		rs.cols = src.cur.cols
+		if h := src.cur.ReportHeader(); rs.report == nil && h != nil {
+			rs.report = &report{header: h}
+		}
+		if len(rs.cols) == 0 {
+			// Empty report without any row.
+			src.release()
+		}
+	}
+	return rs, nil
+}
+
+// fanOutResult is the result of the query on a customer account.
+type fanOutResult struct {
+	rs  *Rows
+	err error
+}
+
+// fanOutSource provides the rows of the customer accounts, in order of the accounts.
+// Each account holds a slot of sem, from the start of its query until it is entirely read.
+type fanOutSource struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results []chan fanOutResult
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	pos     int
+	cur     *Rows
+}
+
+// open waits for the rows of the next account.
+func (s *fanOutSource) open() error {
+	var res fanOutResult
+	select {
+	case res = <-s.results[s.pos]:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	s.pos++
+	if res.err != nil {
+		return res.err
+	}
+	s.cur = res.rs
+	return nil
+}
+
+// release closes the rows of the current account and frees its slot.
+func (s *fanOutSource) release() {
+	_ = s.cur.Close()
+	s.cur = nil
+	<-s.sem
+}
+
+// Next implements the source interface.
+func (s *fanOutSource) Next(dest []driver.Value) error {
+	for {
+		if s.cur != nil {
+			if err := s.cur.Next(dest); err != io.EOF {
+				return err
+			}
+			s.release()
+		}
+		if s.pos >= len(s.results) {
+			return io.EOF
+		}
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+}
+
+// Close implements the source interface.
+// It cancels the pending queries and releases in background the rows already downloaded.
+func (s *fanOutSource) Close() error {
+	s.cancel()
+	var err error
+	if s.cur != nil {
+		err = s.cur.Close()
+		s.cur = nil
+	}
+	pending := s.results[s.pos:]
+	s.pos = len(s.results)
+	go func() {
+		s.wg.Wait()
+		for _, c := range pending {
+			select {
+			case res := <-c:
+				if res.rs != nil {
+					_ = res.rs.Close()
+				}
+			default:
+			}
+		}
+	}()
+	return err
+}
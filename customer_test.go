@@ -0,0 +1,342 @@
+package awql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+const customersResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Body>
+		<getResponse xmlns="https://adwords.google.com/api/adwords/mcm/v201809">
+			<rval>
+				<totalNumEntries>3</totalNumEntries>
+				<entries><customerId>1234567890</customerId><canManageClients>true</canManageClients></entries>
+				<entries><customerId>1112223333</customerId><canManageClients>false</canManageClients></entries>
+				<entries><customerId>4445556666</customerId><canManageClients>false</canManageClients></entries>
+			</rval>
+		</getResponse>
+	</soap:Body>
+</soap:Envelope>`
+
+// newAccountsClient returns a HTTP client responding to the report requests with the report
+// of the customer account. The customer accounts of the manager are listed with customersResponse.
+func newAccountsClient(reports map[string]string, queries *sync.Map) *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Request: rq}
+			if strings.HasSuffix(rq.URL.Path, "ManagedCustomerService") {
+				resp.Body = ioutil.NopCloser(strings.NewReader(customersResponse))
+				return resp, nil
+			}
+			id := rq.Header.Get("clientCustomerId")
+			if queries != nil {
+				_ = rq.ParseForm()
+				queries.Store(id, rq.PostForm.Get("__rdquery"))
+			}
+			r, ok := reports[id]
+			if !ok {
+				resp.StatusCode = http.StatusBadRequest
+				r = "<reportDownloadError><ApiError><type>AuthorizationError.USER_PERMISSION_DENIED</type>" +
+					"<trigger>" + id + "</trigger><fieldPath></fieldPath></ApiError></reportDownloadError>"
+			}
+			resp.Body = ioutil.NopCloser(strings.NewReader(r))
+			return resp, nil
+		}),
+	}
+}
+
+// queryAccounts executes the query on the database and returns the columns and the rows.
+func queryAccounts(db *sql.DB, q string) ([]string, [][]string, error) {
+	rs, err := db.Query(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rs.Close()
+
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var data [][]string
+	for rs.Next() {
+		row := make([]string, len(cols))
+		dest := make([]interface{}, len(cols))
+		for k := range row {
+			dest[k] = &row[k]
+		}
+		if err := rs.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		data = append(data, row)
+	}
+	return cols, data, rs.Err()
+}
+
+// TestWithCustomerIDs tests the fan-out of the queries on multiple customer accounts.
+func TestWithCustomerIDs(t *testing.T) {
+	var (
+		queries sync.Map
+		reports = map[string]string{
+			"111-222-3333": "Campaign,Clicks,Customer ID\na,3,1112223333\nb,1,1112223333\n",
+			"444-555-6666": "Campaign,Clicks,Customer ID\nc,2,4445556666\n",
+		}
+	)
+	dsn := &awql.Dsn{AdwordsID: "111-222-3333, 444-555-6666", DeveloperToken: "dEve1op3er7okeN"}
+	db := sql.OpenDB(newConnector(
+		t, dsn, awql.WithHTTPClient(newAccountsClient(reports, &queries)), awql.WithConcurrency(1),
+	))
+	defer db.Close()
+
+	cols, data, err := queryAccounts(db, "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT ORDER BY Clicks DESC")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if exp := []string{"Campaign", "Clicks", "Customer ID"}; !reflect.DeepEqual(cols, exp) {
+		t.Errorf("Expected %v as columns, received %v", exp, cols)
+	}
+	exp := [][]string{{"a", "3", "1112223333"}, {"c", "2", "4445556666"}, {"b", "1", "1112223333"}}
+	if !reflect.DeepEqual(data, exp) {
+		t.Errorf("Expected %v as rows, received %v", exp, data)
+	}
+	for id := range reports {
+		q, _ := queries.Load(id)
+		if exp := "SELECT CampaignName, Clicks, ExternalCustomerId FROM CAMPAIGN_PERFORMANCE_REPORT"; q != exp {
+			t.Errorf("Expected %s as query on %s, received %v", exp, id, q)
+		}
+	}
+
+	// Aggregates the rows of all the accounts.
+	db = sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(newAccountsClient(map[string]string{
+		"111-222-3333": "Clicks\n3\n1\n",
+		"444-555-6666": "Clicks\n2\n",
+	}, nil))))
+	defer db.Close()
+
+	_, data, err = queryAccounts(db, "SELECT SUM(Clicks) FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if exp := [][]string{{"6"}}; !reflect.DeepEqual(data, exp) {
+		t.Errorf("Expected %v as rows, received %v", exp, data)
+	}
+
+	// Fails if any account fails.
+	db = sql.OpenDB(newConnector(
+		t, dsn,
+		awql.WithHTTPClient(newAccountsClient(reports, nil)),
+		awql.WithCustomerIDs("111-222-3333", "999-999-9999"),
+	))
+	defer db.Close()
+
+	_, _, err = queryAccounts(db, "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if exp := "AuthorizationError.USER_PERMISSION_DENIED (999-999-9999)"; err == nil || err.Error() != exp {
+		t.Errorf("Expected %s as error, received %v", exp, err)
+	}
+	var e *awql.APIError
+	if !errors.As(err, &e) || e.StatusCode != http.StatusBadRequest || !errors.Is(err, awql.ErrAPIAuth) {
+		t.Errorf("Expected an authorization error of the API, received %#v", err)
+	}
+}
+
+// TestWithManagerID tests the fan-out of the queries on the client accounts of a manager account.
+func TestWithManagerID(t *testing.T) {
+	var (
+		queries sync.Map
+		reports = map[string]string{
+			"1112223333": "Customer ID,Clicks\n1112223333,3\n",
+			"4445556666": "Customer ID,Clicks\n4445556666,2\n",
+		}
+	)
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(newAccountsClient(reports, &queries)),
+		awql.WithManagerID("123-456-7890"),
+	))
+	defer db.Close()
+
+	cols, data, err := queryAccounts(db, "SELECT ExternalCustomerId, Clicks FROM ACCOUNT_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if exp := []string{"Customer ID", "Clicks"}; !reflect.DeepEqual(cols, exp) {
+		t.Errorf("Expected %v as columns, received %v", exp, cols)
+	}
+	if exp := [][]string{{"1112223333", "3"}, {"4445556666", "2"}}; !reflect.DeepEqual(data, exp) {
+		t.Errorf("Expected %v as rows, received %v", exp, data)
+	}
+	if _, ok := queries.Load("1234567890"); ok {
+		t.Error("Expected no report of the manager account")
+	}
+}
+
+// TestConnector_Connect_Customers tests the customer accounts required to connect.
+func TestConnector_Connect_Customers(t *testing.T) {
+	var connectTests = []struct {
+		opts []awql.ConnectorOption
+		err  error
+	}{
+		{err: awql.ErrAdwordsID},
+		{opts: []awql.ConnectorOption{awql.WithCustomerIDs()}, err: awql.ErrAdwordsID},
+		{opts: []awql.ConnectorOption{awql.WithCustomerIDs("111-222-3333")}},
+		{opts: []awql.ConnectorOption{awql.WithManagerID("123-456-7890")}},
+	}
+	for i, ct := range connectTests {
+		c := newConnector(t, &awql.Dsn{DeveloperToken: "dEve1op3er7okeN"}, ct.opts...)
+		if _, err := c.Connect(context.Background()); err != ct.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, ct.err, err)
+		}
+	}
+}
+
+// TestWithCustomerIDs_Streaming tests the download of the reports of the accounts as they are read.
+func TestWithCustomerIDs_Streaming(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	client := newAccountsClient(map[string]string{
+		"111-222-3333": "Clicks\n3\n",
+		"444-555-6666": "Clicks\n2\n",
+		"777-888-9999": "Clicks\n1\n",
+	}, nil)
+	next := client.Transport
+	client.Transport = roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+		mu.Lock()
+		ids = append(ids, rq.Header.Get("clientCustomerId"))
+		mu.Unlock()
+		return next.RoundTrip(rq)
+	})
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{AdwordsID: "111-222-3333,444-555-6666,777-888-9999", DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(client),
+		awql.WithConcurrency(1),
+	))
+	defer db.Close()
+
+	rs, err := db.Query("SELECT Clicks FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer rs.Close()
+
+	var clicks []int
+	for k := 0; rs.Next(); k++ {
+		mu.Lock()
+		if n := len(ids); n != k+1 {
+			t.Errorf("%d. Expected %d downloads before reading the row, received %d", k, k+1, n)
+		}
+		mu.Unlock()
+		var c int
+		if err := rs.Scan(&c); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", k, err)
+		}
+		clicks = append(clicks, c)
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if exp := []int{3, 2, 1}; !reflect.DeepEqual(clicks, exp) {
+		t.Errorf("Expected %v as rows, received %v", exp, clicks)
+	}
+}
+
+// TestWithCustomerIDs_Alias tests the aliased columns of the reports without column header on each account.
+func TestWithCustomerIDs_Alias(t *testing.T) {
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{
+			AdwordsID: "111-222-3333,444-555-6666,777-888-9999", DeveloperToken: "dEve1op3er7okeN", SkipColumnHeader: true,
+		},
+		awql.WithHTTPClient(newAccountsClient(map[string]string{
+			"111-222-3333": "3,a,1112223333\n",
+			"444-555-6666": "2,b,4445556666\n",
+			"777-888-9999": "1,c,7778889999\n",
+		}, nil)),
+		awql.WithConcurrency(3),
+	))
+	defer db.Close()
+
+	rs, err := db.Query("SELECT Clicks AS c, CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer rs.Close()
+
+	cols, err := rs.Columns()
+	if exp := []string{"c", "CampaignName", "ExternalCustomerId"}; err != nil || !reflect.DeepEqual(cols, exp) {
+		t.Errorf("Expected %v as columns, received %v (%v)", exp, cols, err)
+	}
+	var n int
+	for ; rs.Next(); n++ {
+		var clicks, name, id interface{}
+		if err := rs.Scan(&clicks, &name, &id); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", n, err)
+		}
+		if _, ok := clicks.(int64); !ok {
+			t.Errorf("%d. Expected the clicks as int64, received %T", n, clicks)
+		}
+		if _, ok := id.(int64); !ok {
+			t.Errorf("%d. Expected the customer ID as int64, received %T", n, id)
+		}
+	}
+	if err := rs.Err(); err != nil || n != 3 {
+		t.Errorf("Expected the rows of the 3 accounts, received %d (%v)", n, err)
+	}
+}
+
+// TestWithManagerID_Retry tests the retries of the requests listing the client accounts.
+func TestWithManagerID_Retry(t *testing.T) {
+	var n int32
+	client := newAccountsClient(map[string]string{
+		"1112223333": "Customer ID,Clicks\n1112223333,3\n",
+		"4445556666": "Customer ID,Clicks\n4445556666,2\n",
+	}, nil)
+	next := client.Transport
+	client.Transport = roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(rq.URL.Path, "ManagedCustomerService") && atomic.AddInt32(&n, 1) == 1 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return next.RoundTrip(rq)
+	})
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(client),
+		awql.WithManagerID("123-456-7890"),
+		awql.WithRetryPolicy(awql.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+	))
+	defer db.Close()
+
+	_, data, err := queryAccounts(db, "SELECT ExternalCustomerId, Clicks FROM ACCOUNT_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if len(data) != 2 || n != 2 {
+		t.Errorf("Expected the rows of the 2 accounts after 2 requests, received %v after %d", data, n)
+	}
+
+	// Wraps the network failure.
+	db = sql.OpenDB(newConnector(
+		t, &awql.Dsn{DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(&http.Client{Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset by peer")
+		})}),
+		awql.WithManagerID("123-456-7890"),
+		awql.WithRetryPolicy(awql.RetryPolicy{MaxAttempts: 1}),
+	))
+	defer db.Close()
+
+	if _, _, err = queryAccounts(db, "SELECT Clicks FROM ACCOUNT_PERFORMANCE_REPORT"); !errors.Is(err, awql.ErrBadNetwork) {
+		t.Errorf("Expected %v as error, received %v", awql.ErrBadNetwork, err)
+	}
+}
@@ -0,0 +1,148 @@
+package awql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+const msgBadDateRange = "invalid date range"
+
+// DateRange is a date range of the DURING clause, to bind on its single placeholder, as DURING ?.
+// It is either a predefined one, named as LAST_7_DAYS, or a custom one with its start and end dates.
+type DateRange struct {
+	Name       string
+	Start, End time.Time
+}
+
+// Predefined date ranges.
+var (
+	Today            = DateRange{Name: "TODAY"}
+	Yesterday        = DateRange{Name: "YESTERDAY"}
+	Last7Days        = DateRange{Name: "LAST_7_DAYS"}
+	LastWeek         = DateRange{Name: "LAST_WEEK"}
+	LastBusinessWeek = DateRange{Name: "LAST_BUSINESS_WEEK"}
+	ThisMonth        = DateRange{Name: "THIS_MONTH"}
+	LastMonth        = DateRange{Name: "LAST_MONTH"}
+	AllTime          = DateRange{Name: "ALL_TIME"}
+	Last14Days       = DateRange{Name: "LAST_14_DAYS"}
+	Last30Days       = DateRange{Name: "LAST_30_DAYS"}
+	ThisWeekSunToday = DateRange{Name: "THIS_WEEK_SUN_TODAY"}
+	ThisWeekMonToday = DateRange{Name: "THIS_WEEK_MON_TODAY"}
+	LastWeekSunSat   = DateRange{Name: "LAST_WEEK_SUN_SAT"}
+)
+
+// NewDateRange returns a custom date range between these dates, both included.
+func NewDateRange(start, end time.Time) DateRange {
+	return DateRange{Start: start, End: end}
+}
+
+// String returns the date range as written in the DURING clause, as LAST_7_DAYS or 20170101,20170131.
+func (d DateRange) String() string {
+	if d.Name != "" {
+		return strings.ToUpper(d.Name)
+	}
+	return d.Start.Format(parser.DateLayout) + "," + d.End.Format(parser.DateLayout)
+}
+
+// valid returns true if the date range is supported by the report of this API version.
+// A predefined date range must be known, a custom one must have its start before its end.
+// Both are also limited by the report definition, if any.
+func (d DateRange) valid(version, report string) bool {
+	r, known := lookupReport(version, report)
+	if d.Name == "" {
+		if d.Start.IsZero() || d.End.IsZero() {
+			return false
+		}
+		// The dates are compared as written in the DURING clause, regardless of the time zone.
+		start, _ := time.Parse(parser.DateLayout, d.Start.Format(parser.DateLayout))
+		end, _ := time.Parse(parser.DateLayout, d.End.Format(parser.DateLayout))
+		if start.After(end) {
+			return false
+		}
+		return !known || r.MaxDays == 0 || int(end.Sub(start).Hours()/24) < r.MaxDays
+	}
+	var ok bool
+	for _, n := range parser.DateRanges {
+		if ok = strings.EqualFold(d.Name, n); ok {
+			break
+		}
+	}
+	if ok && known && len(r.DateRanges) > 0 {
+		ok = false
+		for _, n := range r.DateRanges {
+			if ok = strings.EqualFold(d.Name, n); ok {
+				break
+			}
+		}
+	}
+	return ok
+}
+
+// bindDateRange returns the date range to bind on the placeholder of the statement parsed from the query q.
+// The placeholder must be the only one of the DURING clause of a SELECT statement.
+func bindDateRange(q, version string, st *parser.SelectStmt, p *parser.Placeholder, d DateRange) (string, error) {
+	if st == nil || st.During == nil || st.During.Start != p || st.During.End != nil {
+		return "", ErrQueryBinding
+	}
+	if !d.valid(version, st.From.Name) {
+		return "", newSyntaxError(parser.NewError(q, msgBadDateRange, d.String(), p.Offset))
+	}
+	return d.String(), nil
+}
+
+// checkDuring returns an error if the date range written in the DURING clause of the statement
+// parsed from the query q is not supported by its report. The placeholders are not checked.
+func checkDuring(q, version string, st *parser.SelectStmt) error {
+	if st.During == nil {
+		return nil
+	}
+	var (
+		d   DateRange
+		pos int
+	)
+	switch {
+	case st.During.Range != nil:
+		d, pos = DateRange{Name: st.During.Range.Name}, st.During.Range.Offset
+	default:
+		start, ok := st.During.Start.(*parser.Literal)
+		if !ok {
+			return nil
+		}
+		end, ok := st.During.End.(*parser.Literal)
+		if !ok {
+			return nil
+		}
+		d.Start, _ = time.Parse(parser.DateLayout, start.Raw)
+		d.End, _ = time.Parse(parser.DateLayout, end.Raw)
+		pos = start.Offset
+	}
+	if !d.valid(version, st.From.Name) {
+		return newSyntaxError(parser.NewError(q, msgBadDateRange, d.String(), pos))
+	}
+	return nil
+}
+
+// checkBoundDuring checks the custom date range of the query q once its dates bound
+// on the placeholders of the DURING clause of the source statement st.
+func checkBoundDuring(q, version string, st parser.Statement) error {
+	sel, ok := st.(*parser.SelectStmt)
+	if !ok || sel.During == nil || sel.During.End == nil {
+		return nil
+	}
+	_, start := sel.During.Start.(*parser.Placeholder)
+	_, end := sel.During.End.(*parser.Placeholder)
+	if !start && !end {
+		return nil
+	}
+	bst, err := parseStatement(q)
+	if err != nil {
+		return err
+	}
+	bsel, ok := bst.(*parser.SelectStmt)
+	if !ok {
+		return nil
+	}
+	return checkDuring(q, version, bsel)
+}
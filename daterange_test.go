@@ -0,0 +1,180 @@
+package awql_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"testing"
+	"time"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// TestDateRange_String tests the method String on the DateRange struct.
+func TestDateRange_String(t *testing.T) {
+	var rangeTests = []struct {
+		d   awql.DateRange
+		out string
+	}{
+		{d: awql.Last7Days, out: "LAST_7_DAYS"},
+		{d: awql.DateRange{Name: "this_month"}, out: "THIS_MONTH"},
+		{
+			d:   awql.NewDateRange(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
+			out: "20240101,20240131",
+		},
+	}
+	for i, rt := range rangeTests {
+		if out := rt.d.String(); out != rt.out {
+			t.Errorf("%d. Expected %s, received %s", i, rt.out, out)
+		}
+	}
+}
+
+// TestStmt_Bind_DateRange tests the binding of the date ranges on the DURING clause.
+func TestStmt_Bind_DateRange(t *testing.T) {
+	var (
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	)
+	var bindTests = []struct {
+		q   string
+		a   []driver.Value
+		out string
+		err string
+	}{
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.LastMonth},
+			out: "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING LAST_MONTH",
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE Clicks > ? DURING ?",
+			a:   []driver.Value{10, awql.NewDateRange(start, end)},
+			out: "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE Clicks > 10 DURING 20240101,20240131",
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING ?, ?",
+			a:   []driver.Value{start, end},
+			out: "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING 20240101, 20240131",
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING ?, ?",
+			a:   []driver.Value{awql.Today, end},
+			err: awql.ErrQueryBinding.Error(),
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE CampaignName = ?",
+			a:   []driver.Value{awql.Today},
+			err: awql.ErrQueryBinding.Error(),
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.DateRange{Name: "LAST_YEAR"}},
+			err: "QueryError.INVALID_DATE_RANGE (LAST_YEAR) at position 61",
+		},
+		{
+			q:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.NewDateRange(end, start)},
+			err: "QueryError.INVALID_DATE_RANGE (20240131,20240101) at position 61",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.Yesterday},
+			out: "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING YESTERDAY",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.AllTime},
+			err: "QueryError.INVALID_DATE_RANGE (ALL_TIME) at position 51",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.NewDateRange(start, start)},
+			out: "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING 20240101,20240101",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?",
+			a:   []driver.Value{awql.NewDateRange(start, end)},
+			err: "QueryError.INVALID_DATE_RANGE (20240101,20240131) at position 51",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?, ?",
+			a:   []driver.Value{start, start},
+			out: "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING 20240101, 20240101",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING ?, ?",
+			a:   []driver.Value{start, end},
+			err: "QueryError.INVALID_DATE_RANGE (20240101,20240131) at position 51",
+		},
+	}
+	for i, bt := range bindTests {
+		s := &awql.Stmt{SrcQuery: bt.q}
+		err := s.Bind(bt.a)
+		if bt.err != "" {
+			if err == nil || err.Error() != bt.err {
+				t.Errorf("%d. Expected %s as error, received %v", i, bt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if out := s.String(); out != bt.out {
+			t.Errorf("%d. Expected %s, received %s", i, bt.out, out)
+		}
+	}
+}
+
+// TestDateRange tests the date range given as argument of a query.
+func TestDateRange(t *testing.T) {
+	var rqs []*http.Request
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(newClient(http.StatusOK, "Campaign\nRv\n", &rqs)),
+	))
+	defer db.Close()
+
+	var name string
+	err := db.QueryRow("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING @during", sql.Named("during", awql.Last7Days)).Scan(&name)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	_ = rqs[0].ParseForm()
+	if q, exp := rqs[0].PostForm.Get("__rdquery"), "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING LAST_7_DAYS"; q != exp {
+		t.Errorf("Expected %s as query, received %s", exp, q)
+	}
+}
+
+// TestDateRange_Prepare tests the date range written in the DURING clause of a prepared query.
+func TestDateRange_Prepare(t *testing.T) {
+	db := sql.OpenDB(newConnector(t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}))
+	defer db.Close()
+
+	var prepareTests = []struct {
+		q, err string
+	}{
+		{q: "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING YESTERDAY"},
+		{q: "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING 20240101,20240101"},
+		{q: "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING 20240101,20240131"},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING LAST_7_DAYS",
+			err: "QueryError.INVALID_DATE_RANGE (LAST_7_DAYS) at position 51",
+		},
+		{
+			q:   "SELECT GclId FROM CLICK_PERFORMANCE_REPORT DURING 20240101,20240102",
+			err: "QueryError.INVALID_DATE_RANGE (20240101,20240102) at position 51",
+		},
+	}
+	for i, pt := range prepareTests {
+		st, err := db.Prepare(pt.q)
+		if pt.err == "" && err != nil {
+			t.Errorf("%d. Expected no error, received %v", i, err)
+		} else if pt.err != "" && (err == nil || err.Error() != pt.err) {
+			t.Errorf("%d. Expected %s as error, received %v", i, pt.err, err)
+		}
+		if st != nil {
+			_ = st.Close()
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package awql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"net/http"
@@ -14,6 +15,8 @@ const (
 	APIVersion = "v201809"
 	DsnSep     = "|"
 	DsnOptSep  = ":"
+	DsnIDSep   = ","
+	DsnScheme  = "awql"
 )
 
 // Driver implements all methods to pretend as a sql database driver.
@@ -30,20 +33,41 @@ func init() {
 // @see https://github.com/rvflash/awql-driver#data-source-name for how
 // the DSN string is formatted
 func (d *Driver) Open(dsn string) (driver.Conn, error) {
-	conn, err := unmarshal(dsn)
+	c, err := d.OpenConnector(dsn)
 	if err != nil {
 		return nil, err
 	}
-	if conn.oAuth != nil {
-		// An authentication is required to connect to Adwords API.
-		conn.authenticate()
+	return c.Connect(context.Background())
+}
+
+// OpenConnector parses only once the DSN string and returns a connector
+// to use to open any new connection to the database.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	n, err := unmarshal(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewConnector(n)
+	if err != nil {
+		return nil, err
 	}
-	return conn, nil
+	return c, nil
 }
 
-// parseDsn returns an pointer to an Conn by parsing a DSN string.
+// unmarshal returns a pointer to a Dsn by parsing a DSN string,
+// with the URL format or the pipe format.
 // It throws an error on fails to parse it.
-func unmarshal(dsn string) (*Conn, error) {
+func unmarshal(dsn string) (*Dsn, error) {
+	if strings.HasPrefix(dsn, DsnScheme+"://") {
+		d, err := parseURL(dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.check(); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
 	var adwordsID = func(s string) string {
 		return strings.Split(s, DsnOptSep)[0]
 	}
@@ -66,38 +90,34 @@ func unmarshal(dsn string) (*Conn, error) {
 		return
 	}
 
-	conn := &Conn{}
 	if dsn == "" {
-		return conn, driver.ErrBadConn
+		return nil, driver.ErrBadConn
 	}
-
 	parts := strings.Split(dsn, DsnSep)
 	size := len(parts)
 	if size < 2 || size > 5 || size == 4 {
-		return conn, driver.ErrBadConn
+		return nil, driver.ErrBadConn
 	}
 	// @example 123-456-7890|dEve1op3er7okeN
-	conn.client = http.DefaultClient
-	conn.adwordsID = adwordsID(parts[0])
-	if conn.adwordsID == "" {
-		return conn, ErrAdwordsID
-	}
-	conn.developerToken = parts[1]
-	if conn.developerToken == "" {
-		return conn, ErrDevToken
-	}
-	conn.opts = NewOpts(opts(parts[0]))
+	d := NewDsn(adwordsID(parts[0]))
+	d.DeveloperToken = parts[1]
+	d.APIVersion, d.SupportsZeroImpressions, d.SkipColumnHeader, d.UseRawEnumValues = opts(parts[0])
 
-	var err error
 	switch size {
 	case 3:
 		// @example 123-456-7890|dEve1op3er7okeN|ya29.AcC3s57okeN
-		conn.oAuth, err = NewAuthByToken(parts[2])
+		if parts[2] == "" {
+			return nil, ErrBadToken
+		}
+		d.AccessToken = parts[2]
 	case 5:
 		// @example 123-456-7890|dEve1op3er7okeN|1234567890-c1i3n7iD.apps.googleusercontent.com|c1ien753cr37|1/R3Fr35h-70k3n
-		conn.oAuth, err = NewAuthByClient(parts[2], parts[3], parts[4])
+		d.ClientID, d.ClientSecret, d.RefreshToken = parts[2], parts[3], parts[4]
 	}
-	return conn, err
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d, nil
 }
 
 // AuthToken contains the properties of the Google access token.
@@ -128,16 +148,29 @@ func (a *Auth) IsSet() bool {
 
 // String returns a representation of the access token.
 func (a *Auth) String() string {
-	return a.TokenType + " " + a.AccessToken
+	return a.AuthToken.String()
 }
 
 // Valid returns in success is the access token is not expired.
 // The delta in seconds is used to avoid delay expiration of the token.
 func (a *Auth) Valid() bool {
+	return a.AuthToken.Valid()
+}
+
+// tokenSource returns the source of the access tokens of the authentication.
+// With the keys, the access token is refreshed once expired and shared
+// by all the connections using the same keys. The store, if any, persists it.
+func (a *Auth) tokenSource(client *http.Client, retry RetryPolicy, store TokenStore) TokenSource {
+	if a.IsSet() {
+		key := tokenKey("refresh", a.ClientID, a.RefreshToken)
+		sharedTokens.set(key, &a.AuthToken)
+		return sharedTokenSourceWithStore(key, newRefreshTokenSource(client, a.AuthKey, retry), store)
+	}
 	if a.Expiry.IsZero() {
-		return false
+		// Never valid without expiry.
+		return StaticTokenSource(nil)
 	}
-	return !a.Expiry.Add(-tokenExpiryDelta).Before(time.Now())
+	return StaticTokenSource(&a.AuthToken)
 }
 
 // NewAuthByToken returns an Auth struct only based on the access token.
@@ -179,6 +212,7 @@ type Opts struct {
 }
 
 // NewOpts returns a Opts with default options.
+// The header and the summary of the reports are skipped, see Rows.ReportHeader and Rows.Summary to include them.
 func NewOpts(version string, zero, head, enum bool) *Opts {
 	if version == "" {
 		version = APIVersion
@@ -23,6 +23,16 @@ func TestAwqlDriver_Open(t *testing.T) {
 		// 5
 		{"123-456-7890:v201607|dEve1op3er7okeN|", nil, ErrBadToken},
 		{"123-456-7890|dEve1op3er7okeN||c1ien753cr37|1/R3Fr35h-70k3n", nil, ErrBadToken},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&oops=1", nil, driver.ErrBadConn},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&skipReportHeader=maybe", nil, driver.ErrBadConn},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&accessToken=", nil, ErrBadToken},
+		// 10
+		{"awql://123-456-7890?accessToken=ya29.AcC3s57okeN", nil, ErrDevToken},
+		{"awql://?developerToken=dEve1op3er7okeN", nil, ErrAdwordsID},
+		{"awql://123-456-7890/path?developerToken=dEve1op3er7okeN", nil, driver.ErrBadConn},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&clientId=c1i3n7iD", nil, ErrBadToken},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&cacheMode=never", nil, driver.ErrBadConn},
+		{"awql://123-456-7890?developerToken=dEve1op3er7okeN&validate=maybe", nil, driver.ErrBadConn},
 
 		// Ok.
 		{
@@ -34,7 +44,7 @@ func TestAwqlDriver_Open(t *testing.T) {
 			"123-456-7890|dEve1op3er7okeN|ya29.AcC3s57okeN",
 			&Conn{
 				adwordsID: "123-456-7890", developerToken: "dEve1op3er7okeN",
-				oAuth: &Auth{AuthKey{}, AuthToken{AccessToken: "ya29.AcC3s57okeN"}},
+				tokens: StaticTokenSource(&AuthToken{AccessToken: "ya29.AcC3s57okeN"}),
 			},
 			nil,
 		},
@@ -42,30 +52,43 @@ func TestAwqlDriver_Open(t *testing.T) {
 			"123-456-7890:v201607|dEve1op3er7okeN|ya29.AcC3s57okeN",
 			&Conn{
 				adwordsID: "123-456-7890", developerToken: "dEve1op3er7okeN",
-				oAuth: &Auth{AuthKey{}, AuthToken{AccessToken: "ya29.AcC3s57okeN"}},
-				opts:  &Opts{Version: "v201607"},
+				tokens: StaticTokenSource(&AuthToken{AccessToken: "ya29.AcC3s57okeN"}),
+				opts:   &Opts{Version: "v201607"},
 			},
 			nil,
 		},
-		// 10
 		{
 			"123-456-7890|dEve1op3er7okeN|1234567890-c1i3n7iD.apps.googleusercontent.com|c1ien753cr37|1/R3Fr35h-70k3n",
 			&Conn{
 				adwordsID: "123-456-7890", developerToken: "dEve1op3er7okeN",
-				oAuth: &Auth{
-					AuthKey{
-						ClientID:     "1234567890-c1i3n7iD.apps.googleusercontent.com",
-						ClientSecret: "c1ien753cr37", RefreshToken: "1/R3Fr35h-70k3n",
-					},
-					AuthToken{
+				tokens: ReuseTokenSource(
+					&AuthToken{
 						AccessToken: "ya29.AcC3s57okeN",
 						TokenType:   "Bearer",
 						Expiry:      time.Now().Add(tokenExpiryDuration),
 					},
-				},
+					RefreshTokenSource(nil, AuthKey{
+						ClientID:     "1234567890-c1i3n7iD.apps.googleusercontent.com",
+						ClientSecret: "c1ien753cr37", RefreshToken: "1/R3Fr35h-70k3n",
+					}),
+				),
 			},
 			nil,
 		},
+		{
+			"awql://123-456-7890?developerToken=dEve1op3er7okeN&accessToken=ya29.AcC3s57okeN&version=v201607",
+			&Conn{
+				adwordsID: "123-456-7890", developerToken: "dEve1op3er7okeN",
+				tokens: StaticTokenSource(&AuthToken{AccessToken: "ya29.AcC3s57okeN"}),
+				opts:   &Opts{Version: "v201607"},
+			},
+			nil,
+		},
+		{
+			"awql://?developerToken=dEve1op3er7okeN&managerId=123-456-7890&skipReportSummary=false",
+			&Conn{managerID: "123-456-7890", developerToken: "dEve1op3er7okeN"},
+			nil,
+		},
 	}
 
 	d := &Driver{}
@@ -82,6 +105,34 @@ func TestAwqlDriver_Open(t *testing.T) {
 	}
 }
 
+// TestAwqlDriver_Open_CacheMode tests the cache mode of the data source name.
+func TestAwqlDriver_Open_CacheMode(t *testing.T) {
+	c, err := (&Driver{}).Open("awql://123-456-7890?developerToken=dEve1op3er7okeN&cacheDir=%2Ftmp&cacheMode=bypass")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if conn := c.(*Conn); conn.cache == nil || conn.cacheMode != CacheBypass {
+		t.Errorf("Expected the cache with the %s mode, received %s", CacheBypass, conn.cacheMode)
+	}
+}
+
+// TestAwqlDriver_Open_Validate tests the validation of the queries, disabled by the data source name.
+func TestAwqlDriver_Open_Validate(t *testing.T) {
+	for dsn, ok := range map[string]bool{
+		"awql://123-456-7890?developerToken=dEve1op3er7okeN":                true,
+		"awql://123-456-7890?developerToken=dEve1op3er7okeN&validate=true":  true,
+		"awql://123-456-7890?developerToken=dEve1op3er7okeN&validate=false": false,
+	} {
+		c, err := (&Driver{}).Open(dsn)
+		if err != nil {
+			t.Fatalf("Expected no error with %s, received %v", dsn, err)
+		}
+		if conn := c.(*Conn); conn.noValidate == ok {
+			t.Errorf("Expected the validation of the queries with %s: %t", dsn, ok)
+		}
+	}
+}
+
 var authTests = []struct {
 	token          *Auth  // in
 	str            string // out
@@ -146,3 +197,28 @@ func TestAwqlAuth_Valid(t *testing.T) {
 		}
 	}
 }
+
+// TestUnmarshal tests the function named unmarshal with the URL format.
+func TestUnmarshal(t *testing.T) {
+	var dsnTests = []*Dsn{
+		{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", AccessToken: "ya29.AcC3s57okeN"},
+		{
+			AdwordsID: "123-456-7890,234-567-8901", APIVersion: "v201809", DeveloperToken: "dEve1op3er7okeN",
+			ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/R3Fr35h-70k3n",
+			SupportsZeroImpressions: true, SkipColumnHeader: true, UseRawEnumValues: true,
+			IncludeReportHeader: true, IncludeReportSummary: true,
+			Concurrency: 3, Retry: RetryPolicy{MaxAttempts: 1, MinBackoff: time.Second, MaxBackoff: time.Minute},
+			TokenFile: "/tmp/awql.json", CacheDir: "/tmp/awql", CacheTTL: time.Hour, CacheMaxSize: 1 << 20,
+		},
+		{DeveloperToken: "dEve1op3er7okeN", ManagerID: "123-456-7890", AccessToken: "ya29.AcC3s57okeN"},
+	}
+	for i, d := range dsnTests {
+		u, err := unmarshal(d.URL())
+		if err != nil {
+			t.Fatalf("%d. Expected no error with %s, received %v", i, d.URL(), err)
+		}
+		if *u != *d {
+			t.Errorf("%d. Expected %v, received %v", i, d, u)
+		}
+	}
+}
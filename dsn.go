@@ -1,16 +1,70 @@
 package awql
 
-import "strconv"
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Options of the data source name with the URL format.
+const (
+	dsnDeveloperToken    = "developerToken"
+	dsnVersion           = "version"
+	dsnZeroImpressions   = "includeZeroImpressions"
+	dsnSkipColumnHeader  = "skipColumnHeader"
+	dsnRawEnumValues     = "useRawEnumValues"
+	dsnSkipReportHeader  = "skipReportHeader"
+	dsnSkipReportSummary = "skipReportSummary"
+	dsnAccessToken       = "accessToken"
+	dsnClientID          = "clientId"
+	dsnClientSecret      = "clientSecret"
+	dsnRefreshToken      = "refreshToken"
+	dsnServiceAccount    = "serviceAccount"
+	dsnImpersonatedUser  = "impersonatedUser"
+	dsnManagerID         = "managerId"
+	dsnConcurrency       = "concurrency"
+	dsnMaxAttempts       = "maxAttempts"
+	dsnMinBackoff        = "minBackoff"
+	dsnMaxBackoff        = "maxBackoff"
+	dsnTokenFile         = "tokenFile"
+	dsnCacheDir          = "cacheDir"
+	dsnCacheTTL          = "cacheTTL"
+	dsnCacheMaxSize      = "cacheMaxSize"
+	dsnCacheMode         = "cacheMode"
+	dsnValidate          = "validate"
+)
 
 // Dsn represents a data source name.
+// AdwordsID can list multiple customer IDs separated by a comma.
+// ServiceAccount is the JSON key of a service account, inline or the path of the key file.
+// ImpersonatedUser is the email address of the user impersonated by the service account, if any.
+// TokenFile is the path of the file used to persist the access tokens, see NewFileTokenStore.
+// With CacheDir, the reports are cached in this directory, see NewCache, and used with CacheMode.
+// With SkipValidation, the queries are not checked against the catalog of the reports, see WithValidation.
+// The zero value of Concurrency and of each property of Retry means their default value.
 type Dsn struct {
 	AdwordsID, APIVersion,
 	DeveloperToken, AccessToken,
 	ClientID, ClientSecret,
-	RefreshToken string
+	RefreshToken,
+	ServiceAccount,
+	ImpersonatedUser,
+	ManagerID,
+	TokenFile,
+	CacheDir string
 	SkipColumnHeader,
 	SupportsZeroImpressions,
-	UseRawEnumValues bool
+	UseRawEnumValues,
+	IncludeReportHeader,
+	IncludeReportSummary,
+	SkipValidation bool
+	Concurrency  int
+	Retry        RetryPolicy
+	CacheTTL     time.Duration
+	CacheMaxSize int64
+	CacheMode    CacheMode
 }
 
 // NewDsn returns a new instance of Dsn.
@@ -19,12 +73,16 @@ func NewDsn(id string) *Dsn {
 }
 
 // String outputs the data source name as string.
+// The URL format is only used if the properties can not be expressed with the pipe format.
 // Output:
 // 123-456-7890:v201607:true:false:false|dEve1op3er7okeN|1234567890-c1i3n7iD.com|c1ien753cr37|1/R3Fr35h-70k3n
 func (d *Dsn) String() (n string) {
-	if d.AdwordsID == "" {
+	if d.AdwordsID == "" && d.ManagerID == "" {
 		return
 	}
+	if d.extended() {
+		return d.URL()
+	}
 
 	n = d.AdwordsID
 	n += DsnOptSep + d.APIVersion
@@ -50,3 +108,203 @@ func (d *Dsn) String() (n string) {
 
 	return
 }
+
+// URL outputs the data source name with the URL format.
+// Only the properties without their default value are set as option.
+// Output:
+// awql://123-456-7890?clientId=1234567890-c1i3n7iD.com&clientSecret=c1ien753cr37&developerToken=dEve1op3er7okeN&refreshToken=1%2FR3Fr35h-70k3n
+func (d *Dsn) URL() string {
+	v := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+	setBool := func(name string, value, def bool) {
+		if value != def {
+			v.Set(name, strconv.FormatBool(value))
+		}
+	}
+	setInt := func(name string, value int64) {
+		if value != 0 {
+			v.Set(name, strconv.FormatInt(value, 10))
+		}
+	}
+	setDuration := func(name string, value time.Duration) {
+		if value != 0 {
+			v.Set(name, value.String())
+		}
+	}
+	set(dsnDeveloperToken, d.DeveloperToken)
+	set(dsnVersion, d.APIVersion)
+	setBool(dsnZeroImpressions, d.SupportsZeroImpressions, false)
+	setBool(dsnSkipColumnHeader, d.SkipColumnHeader, false)
+	setBool(dsnRawEnumValues, d.UseRawEnumValues, false)
+	setBool(dsnSkipReportHeader, !d.IncludeReportHeader, true)
+	setBool(dsnSkipReportSummary, !d.IncludeReportSummary, true)
+	set(dsnAccessToken, d.AccessToken)
+	set(dsnClientID, d.ClientID)
+	set(dsnClientSecret, d.ClientSecret)
+	set(dsnRefreshToken, d.RefreshToken)
+	set(dsnServiceAccount, d.ServiceAccount)
+	set(dsnImpersonatedUser, d.ImpersonatedUser)
+	set(dsnManagerID, d.ManagerID)
+	setInt(dsnConcurrency, int64(d.Concurrency))
+	setInt(dsnMaxAttempts, int64(d.Retry.MaxAttempts))
+	setDuration(dsnMinBackoff, d.Retry.MinBackoff)
+	setDuration(dsnMaxBackoff, d.Retry.MaxBackoff)
+	set(dsnTokenFile, d.TokenFile)
+	set(dsnCacheDir, d.CacheDir)
+	setDuration(dsnCacheTTL, d.CacheTTL)
+	setInt(dsnCacheMaxSize, d.CacheMaxSize)
+	if d.CacheMode != CacheUse {
+		v.Set(dsnCacheMode, d.CacheMode.String())
+	}
+	setBool(dsnValidate, !d.SkipValidation, true)
+
+	u := DsnScheme + "://" + d.AdwordsID
+	if len(v) == 0 {
+		return u
+	}
+	return u + "?" + v.Encode()
+}
+
+// extended returns true if the data source name uses properties only available with the URL format.
+func (d *Dsn) extended() bool {
+	return d.AdwordsID == "" || d.ServiceAccount != "" || d.ImpersonatedUser != "" || d.ManagerID != "" ||
+		d.TokenFile != "" || d.CacheDir != "" || d.IncludeReportHeader || d.IncludeReportSummary ||
+		d.Concurrency != 0 || d.Retry != (RetryPolicy{}) || d.CacheTTL != 0 || d.CacheMaxSize != 0 ||
+		d.CacheMode != CacheUse || d.SkipValidation
+}
+
+// auth returns the authentication described by the data source name.
+// It returns nil if no credential is provided.
+func (d *Dsn) auth() (*Auth, error) {
+	switch {
+	case d.AccessToken != "":
+		return NewAuthByToken(d.AccessToken)
+	case d.ClientID != "" || d.ClientSecret != "" || d.RefreshToken != "":
+		return NewAuthByClient(d.ClientID, d.ClientSecret, d.RefreshToken)
+	}
+	return nil, nil
+}
+
+// tokenSource returns the source of the access tokens described by the data source name,
+// persisted by the store, if any. It returns nil if no credential is provided.
+func (d *Dsn) tokenSource(client *http.Client, retry RetryPolicy, store TokenStore) (TokenSource, error) {
+	if d.ServiceAccount != "" {
+		key, err := readServiceAccountKey(d.ServiceAccount)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := newServiceAccountTokenSource(client, key, d.ImpersonatedUser, retry)
+		if err != nil {
+			return nil, err
+		}
+		return sharedTokenSourceWithStore(ts.cacheKey(), ts, store), nil
+	}
+	a, err := d.auth()
+	if err != nil || a == nil {
+		return nil, err
+	}
+	return a.tokenSource(client, retry, store), nil
+}
+
+// check returns an error if the data source name can not be used to connect.
+func (d *Dsn) check() error {
+	if d.AdwordsID == "" && d.ManagerID == "" {
+		return ErrAdwordsID
+	}
+	if d.DeveloperToken == "" {
+		return ErrDevToken
+	}
+	_, err := d.tokenSource(nil, DefaultRetryPolicy, nil)
+	return err
+}
+
+// parseURL returns the data source name of the DSN string with the URL format.
+// @example awql://123-456-7890?developerToken=dEve1op3er7okeN&accessToken=ya29.AcC3s57okeN
+func parseURL(dsn string) (*Dsn, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme != DsnScheme || u.Path != "" && u.Path != "/" {
+		return nil, driver.ErrBadConn
+	}
+	d := NewDsn(u.Host)
+
+	strs := map[string]*string{
+		dsnDeveloperToken:   &d.DeveloperToken,
+		dsnVersion:          &d.APIVersion,
+		dsnAccessToken:      &d.AccessToken,
+		dsnClientID:         &d.ClientID,
+		dsnClientSecret:     &d.ClientSecret,
+		dsnRefreshToken:     &d.RefreshToken,
+		dsnServiceAccount:   &d.ServiceAccount,
+		dsnImpersonatedUser: &d.ImpersonatedUser,
+		dsnManagerID:        &d.ManagerID,
+		dsnTokenFile:        &d.TokenFile,
+		dsnCacheDir:         &d.CacheDir,
+	}
+	bools := map[string]*bool{
+		dsnZeroImpressions:  &d.SupportsZeroImpressions,
+		dsnSkipColumnHeader: &d.SkipColumnHeader,
+		dsnRawEnumValues:    &d.UseRawEnumValues,
+	}
+	durations := map[string]*time.Duration{
+		dsnMinBackoff: &d.Retry.MinBackoff,
+		dsnMaxBackoff: &d.Retry.MaxBackoff,
+		dsnCacheTTL:   &d.CacheTTL,
+	}
+	var b bool
+	for name, values := range u.Query() {
+		v := values[len(values)-1]
+		if p, ok := strs[name]; ok {
+			*p = v
+			continue
+		}
+		if p, ok := durations[name]; ok {
+			if *p, err = time.ParseDuration(v); err != nil {
+				return nil, driver.ErrBadConn
+			}
+			continue
+		}
+		if p, ok := bools[name]; ok {
+			if *p, err = strconv.ParseBool(v); err != nil {
+				return nil, driver.ErrBadConn
+			}
+			continue
+		}
+		switch name {
+		case dsnSkipReportHeader:
+			b, err = strconv.ParseBool(v)
+			d.IncludeReportHeader = !b
+		case dsnSkipReportSummary:
+			b, err = strconv.ParseBool(v)
+			d.IncludeReportSummary = !b
+		case dsnValidate:
+			b, err = strconv.ParseBool(v)
+			d.SkipValidation = !b
+		case dsnConcurrency:
+			d.Concurrency, err = strconv.Atoi(v)
+		case dsnMaxAttempts:
+			d.Retry.MaxAttempts, err = strconv.Atoi(v)
+		case dsnCacheMaxSize:
+			d.CacheMaxSize, err = strconv.ParseInt(v, 10, 64)
+		case dsnCacheMode:
+			m, ok := cacheModes[v]
+			if !ok {
+				return nil, driver.ErrBadConn
+			}
+			d.CacheMode = m
+		default:
+			// Unknown option.
+			return nil, driver.ErrBadConn
+		}
+		if err != nil {
+			return nil, driver.ErrBadConn
+		}
+	}
+	if d.AccessToken == "" && len(u.Query()[dsnAccessToken]) > 0 {
+		return nil, ErrBadToken
+	}
+	return d, nil
+}
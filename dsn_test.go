@@ -2,6 +2,7 @@ package awql_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/rvflash/awql-driver"
 )
@@ -50,6 +51,15 @@ func TestNewDsn(t *testing.T) {
 			},
 			s: "123-456-7890:v201609:false:false:false|dEve1op3er7okeN|1234567890-Aw91.apps.googleusercontent.com|C13nt5e0r3t|1/n-R3fr35h70k3n",
 		},
+
+		{
+			d: &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", IncludeReportSummary: true},
+			s: "awql://123-456-7890?developerToken=dEve1op3er7okeN&skipReportSummary=false",
+		},
+		{
+			d: &awql.Dsn{DeveloperToken: "dEve1op3er7okeN", ManagerID: "123-456-7890"},
+			s: "awql://?developerToken=dEve1op3er7okeN&managerId=123-456-7890",
+		},
 	}
 
 	for i, dt := range dsnTests {
@@ -58,3 +68,37 @@ func TestNewDsn(t *testing.T) {
 		}
 	}
 }
+
+// TestDsn_URL tests the method URL on the Dsn struct.
+func TestDsn_URL(t *testing.T) {
+	var dsnTests = []struct {
+		d *awql.Dsn
+		s string
+	}{
+		{d: awql.NewDsn("123-456-7890"), s: "awql://123-456-7890"},
+		{
+			d: &awql.Dsn{
+				AdwordsID: "123-456-7890,234-567-8901", APIVersion: "v201809", DeveloperToken: "dEve1op3er7okeN",
+				SupportsZeroImpressions: true, IncludeReportHeader: true,
+				ClientID: "1234567890-Aw91.apps.googleusercontent.com", ClientSecret: "C13nt5e0r3t", RefreshToken: "1/n-R3fr35h70k3n",
+			},
+			s: "awql://123-456-7890,234-567-8901?clientId=1234567890-Aw91.apps.googleusercontent.com&clientSecret=C13nt5e0r3t" +
+				"&developerToken=dEve1op3er7okeN&includeZeroImpressions=true&refreshToken=1%2Fn-R3fr35h70k3n" +
+				"&skipReportHeader=false&version=v201809",
+		},
+		{
+			d: &awql.Dsn{
+				AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", Concurrency: 10,
+				Retry:    awql.RetryPolicy{MaxAttempts: 5, MinBackoff: 2 * time.Second},
+				CacheDir: "/tmp/awql", CacheTTL: time.Hour, CacheMode: awql.CacheRefresh, SkipValidation: true,
+			},
+			s: "awql://123-456-7890?cacheDir=%2Ftmp%2Fawql&cacheMode=refresh&cacheTTL=1h0m0s&concurrency=10" +
+				"&developerToken=dEve1op3er7okeN&maxAttempts=5&minBackoff=2s&validate=false",
+		},
+	}
+	for i, dt := range dsnTests {
+		if s := dt.d.URL(); s != dt.s {
+			t.Errorf("%d. Expected %v, received %v", i, dt.s, s)
+		}
+	}
+}
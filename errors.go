@@ -1,57 +1,150 @@
 package awql
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/rvflash/awql-driver/parser"
 )
 
 // Error messages.
 var (
-	ErrQuery        = NewQueryError("missing")
-	ErrQueryBinding = NewQueryError("binding not match")
-	ErrNoDsn        = NewConnectionError("missing data source")
-	ErrNoNetwork    = NewConnectionError("not found")
-	ErrBadNetwork   = NewConnectionError("service unavailable")
-	ErrBadToken     = NewConnectionError("invalid access token")
-	ErrAdwordsID    = NewConnectionError("adwords id")
-	ErrDevToken     = NewConnectionError("developer token")
+	ErrQuery          = NewQueryError("missing")
+	ErrQueryBinding   = NewQueryError("binding not match")
+	ErrQueryValue     = NewQueryError("unsupported value")
+	ErrNoDsn          = NewConnectionError("missing data source")
+	ErrNoNetwork      = NewConnectionError("not found")
+	ErrBadNetwork     = NewConnectionError("service unavailable")
+	ErrBadToken       = NewConnectionError("invalid access token")
+	ErrAdwordsID      = NewConnectionError("adwords id")
+	ErrDevToken       = NewConnectionError("developer token")
+	ErrNoCustomer     = NewConnectionError("no customer account")
+	ErrServiceAccount = NewConnectionError("invalid service account")
+	ErrBadResponse    = NewConnectionError("invalid response")
 )
 
+// Categories of the errors returned by the Adwords API, to use with errors.Is.
+// ErrAPIAuth is also the category of the credentials rejected by the Google token endpoint.
+// @see https://developers.google.com/adwords/api/docs/common-errors
+var (
+	ErrAPIAuth      = errors.New("api authentication error")
+	ErrAPIRateLimit = errors.New("api rate limit exceeded")
+	ErrAPIQuery     = errors.New("api query error")
+	ErrAPIQuota     = errors.New("api quota exceeded")
+	ErrAPIInternal  = errors.New("api internal error")
+)
+
+// apiCategories lists by category the prefixes of the types of the API errors.
+var apiCategories = map[error][]string{
+	ErrAPIAuth: {
+		"AuthenticationError.",
+		"AuthorizationError.",
+		"OAuthTokenHeaderError.",
+		"ReportDownloadError.INVALID_AUTH_TOKEN",
+	},
+	ErrAPIRateLimit: {
+		"RateExceededError.",
+	},
+	ErrAPIQuery: {
+		"QueryError.",
+		"ReportDefinitionError.",
+		"SelectorError.",
+		"ReportDownloadError.INVALID_PARAMETER",
+	},
+	ErrAPIQuota: {
+		"QuotaCheckError.",
+	},
+	ErrAPIInternal: {
+		"InternalApiError.",
+		"DatabaseError.",
+		"ReportDownloadError.ERROR_GETTING_RESPONSE_FROM_BACKEND",
+	},
+}
+
+// requestIDHeader is the name of the HTTP header with the ID of the request to the Adwords API.
+const requestIDHeader = "RequestId"
+
 // APIError represents a Google Report Download Error.
-// It voluntary ignores trigger field.
+// Type, Trigger and Field describe the first error of the response,
+// Errors lists all of them. When known, StatusCode is the HTTP status code
+// of the response and RequestID the ID of the request to the API.
 //
 // In case of error, Google Adwords API provides more information in a XML response:
 //
-// 	<reportDownloadError>
-// 		<ApiError>
+//	<reportDownloadError>
+//		<ApiError>
 //			<type>ReportDefinitionError.CUSTOMER_SERVING_TYPE_REPORT_MISMATCH</type>
 //			<trigger></trigger>
 //			<fieldPath>selector</fieldPath>
-// 		</ApiError>
+//		</ApiError>
 //	</reportDownloadError>
 //
+// Use errors.Is with ErrAPIAuth, ErrAPIRateLimit, ErrAPIQuery, ErrAPIQuota
+// or ErrAPIInternal to check its category.
 type APIError struct {
-	Type    string `xml:"ApiError>type"`
-	Trigger string `xml:"ApiError>trigger"`
-	Field   string `xml:"ApiError>fieldPath"`
+	Type,
+	Trigger,
+	Field string
+	Errors     []APIErrorDetail
+	StatusCode int
+	RequestID  string
+}
+
+// APIErrorDetail represents one of the errors of the response of the Adwords API.
+type APIErrorDetail struct {
+	Type    string `xml:"type"`
+	Trigger string `xml:"trigger"`
+	Field   string `xml:"fieldPath"`
 }
 
 // NewAPIError parses a XML document that represents a download report error.
-// It returns the given message as error.
+// It returns the given message as error, ErrBadResponse if the document is empty or invalid.
 func NewAPIError(d []byte) error {
-	if len(d) == 0 {
-		return ErrNoDsn
+	return newAPIError(0, "", d)
+}
+
+// newAPIError parses the XML document of the response in error
+// with this HTTP status code and request ID.
+// With a status code, an empty or invalid document gives an APIError without type.
+func newAPIError(status int, requestID string, d []byte) error {
+	fail := func(err error) error {
+		if status == 0 {
+			return err
+		}
+		return &APIError{StatusCode: status, RequestID: requestID}
+	}
+	if len(bytes.TrimSpace(d)) == 0 {
+		return fail(ErrBadResponse)
+	}
+	var doc struct {
+		Errors []APIErrorDetail `xml:"ApiError"`
+	}
+	if err := xml.Unmarshal(d, &doc); err != nil {
+		return fail(wrapConnectionError(ErrBadResponse, err))
 	}
-	e := &APIError{}
-	err := xml.Unmarshal(d, e)
-	if err != nil {
-		e.Type = err.Error()
+	if len(doc.Errors) == 0 {
+		return fail(ErrBadResponse)
+	}
+	return &APIError{
+		Type:       doc.Errors[0].Type,
+		Trigger:    doc.Errors[0].Trigger,
+		Field:      doc.Errors[0].Field,
+		Errors:     doc.Errors,
+		StatusCode: status,
+		RequestID:  requestID,
 	}
-	return e
 }
 
 // String returns a representation of the api error.
+// Without type, it returns the HTTP status of the response, as 503 Service Unavailable.
 func (e *APIError) Error() string {
+	if e.Type == "" && e.StatusCode != 0 {
+		return strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+	}
 	switch e.Field {
 	case "":
 		if e.Trigger == "" || e.Trigger == "<null>" {
@@ -65,34 +158,116 @@ func (e *APIError) Error() string {
 	}
 }
 
+// Is returns true if the target is the category of one of the errors of the response.
+// Without type, the category is deduced from the HTTP status code.
+func (e *APIError) Is(target error) bool {
+	prefixes, ok := apiCategories[target]
+	if !ok {
+		return false
+	}
+	types := []string{e.Type}
+	for _, d := range e.Errors {
+		types = append(types, d.Type)
+	}
+	for _, t := range types {
+		for _, p := range prefixes {
+			if t != "" && strings.HasPrefix(t, p) {
+				return true
+			}
+		}
+	}
+	if e.Type != "" {
+		return false
+	}
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return target == ErrAPIAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return target == ErrAPIRateLimit
+	case e.StatusCode >= http.StatusInternalServerError:
+		return target == ErrAPIInternal
+	}
+	return false
+}
+
 // ConnectionError represents an connection error.
+// When known, the underlying cause is returned by Unwrap.
 type ConnectionError struct {
-	s string
+	s   string
+	err error
 }
 
 // NewConnectionError returns an error of type Connection with the given text.
 func NewConnectionError(text string) error {
-	return &ConnectionError{formatError(text)}
+	return &ConnectionError{s: formatError(text)}
 }
 
 // Error outputs a connection error message.
 func (e *ConnectionError) Error() string {
+	if e.err != nil {
+		return "ConnectionError." + e.s + " (" + e.err.Error() + ")"
+	}
 	return "ConnectionError." + e.s
 }
 
+// Unwrap returns the underlying cause of the connection error, if any.
+func (e *ConnectionError) Unwrap() error {
+	return e.err
+}
+
+// wrapConnectionError returns a copy of the connection error with this underlying cause.
+func wrapConnectionError(e, cause error) error {
+	return &ConnectionError{s: e.(*ConnectionError).s, err: cause}
+}
+
+// Is returns true if the target is a connection error with the same message.
+func (e *ConnectionError) Is(target error) bool {
+	t, ok := target.(*ConnectionError)
+	return ok && t.s == e.s
+}
+
 // QueryError represents a query error.
+// When known, Trigger is the offending token and Pos its position in
+// characters in the query, starting at 1.
+// The underlying cause, as the syntax error of the parser, is returned by Unwrap.
 type QueryError struct {
-	s string
+	s       string
+	err     error
+	Trigger string
+	Pos     int
 }
 
 // NewQueryError returns an error of type Internal with the given text.
 func NewQueryError(text string) error {
-	return &QueryError{formatError(text)}
+	return &QueryError{s: formatError(text)}
+}
+
+// newSyntaxError returns a query error based on the syntax error of the parser.
+func newSyntaxError(err *parser.Error) error {
+	return &QueryError{s: formatError(err.Msg), err: err, Trigger: err.Token, Pos: err.Pos}
 }
 
 // Error outputs a query error message.
 func (e *QueryError) Error() string {
-	return "QueryError." + e.s
+	s := "QueryError." + e.s
+	if e.Trigger != "" {
+		s += " (" + e.Trigger + ")"
+	}
+	if e.Pos > 0 {
+		s += " at position " + strconv.Itoa(e.Pos)
+	}
+	return s
+}
+
+// Unwrap returns the underlying cause of the query error, if any.
+func (e *QueryError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true if the target is a query error with the same message.
+func (e *QueryError) Is(target error) bool {
+	t, ok := target.(*QueryError)
+	return ok && t.s == e.s
 }
 
 // formatError returns a string in upper case with underscore instead of space.
@@ -1,12 +1,20 @@
 package awql
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rvflash/awql-driver/parser"
+)
 
 var reportDefinitionErrorTests = []struct {
 	xml []byte // in
 	err string // out
 }{
-	{[]byte(""), ErrNoDsn.Error()},
+	{[]byte(""), ErrBadResponse.Error()},
 	{[]byte(`
 	<reportDownloadError>
 		<ApiError>
@@ -39,7 +47,8 @@ var reportDefinitionErrorTests = []struct {
 			<fieldPath>CampaignId</fieldPath>
 		</ApiError>
 	</reportDownloadError>`), "ReportDefinitionError.INVALID_FIELD_NAME_FOR_REPORT on CampaignId"},
-	{[]byte(`Not a XML`), "EOF"},
+	{[]byte(`Not a XML`), "ConnectionError.INVALID_RESPONSE (EOF)"},
+	{[]byte(`<html></html>`), ErrBadResponse.Error()},
 }
 
 // TestNewApiError tests the method named NewAPIError.
@@ -55,6 +64,122 @@ func TestNewApiError(t *testing.T) {
 	}
 }
 
+// TestNewAPIError_Errors tests the parsing of all the errors of the response.
+func TestNewAPIError_Errors(t *testing.T) {
+	err := newAPIError(http.StatusBadRequest, "00056d2b", []byte(`
+	<reportDownloadError>
+		<ApiError>
+			<type>ReportDefinitionError.INVALID_FIELD_NAME_FOR_REPORT</type>
+			<trigger></trigger>
+			<fieldPath>CampaignId</fieldPath>
+		</ApiError>
+		<ApiError>
+			<type>QuotaCheckError.INCOMPLETE_SIGNUP</type>
+			<trigger>Sign up</trigger>
+			<fieldPath></fieldPath>
+		</ApiError>
+	</reportDownloadError>`))
+
+	var e *APIError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected an API error, received %v", err)
+	}
+	exp := &APIError{
+		Type:  "ReportDefinitionError.INVALID_FIELD_NAME_FOR_REPORT",
+		Field: "CampaignId",
+		Errors: []APIErrorDetail{
+			{Type: "ReportDefinitionError.INVALID_FIELD_NAME_FOR_REPORT", Field: "CampaignId"},
+			{Type: "QuotaCheckError.INCOMPLETE_SIGNUP", Trigger: "Sign up"},
+		},
+		StatusCode: http.StatusBadRequest,
+		RequestID:  "00056d2b",
+	}
+	if !reflect.DeepEqual(e, exp) {
+		t.Errorf("Expected %#v, received %#v", exp, e)
+	}
+	if !errors.Is(err, ErrAPIQuery) || !errors.Is(err, ErrAPIQuota) || errors.Is(err, ErrAPIAuth) {
+		t.Errorf("Expected the categories of all the errors with %v", err)
+	}
+}
+
+// TestNewAPIError_Status tests the error of a response in error without valid document.
+func TestNewAPIError_Status(t *testing.T) {
+	for i, d := range []string{"", "Not a XML", "<html></html>"} {
+		err := newAPIError(http.StatusBadGateway, "00056d2b", []byte(d))
+		exp := &APIError{StatusCode: http.StatusBadGateway, RequestID: "00056d2b"}
+		if !reflect.DeepEqual(err, exp) {
+			t.Errorf("%d. Expected %#v, received %#v", i, exp, err)
+		}
+		if s := err.Error(); s != "502 Bad Gateway" {
+			t.Errorf("%d. Expected the HTTP status as message, received %s", i, s)
+		}
+	}
+}
+
+// TestAPIError_Is tests the method named Is on APIError.
+func TestAPIError_Is(t *testing.T) {
+	var isTests = []struct {
+		err    *APIError
+		target error
+		ok     bool
+	}{
+		{err: &APIError{}, target: ErrAPIAuth},
+		{err: &APIError{Type: "AuthenticationError.OAUTH_TOKEN_INVALID"}, target: ErrAPIAuth, ok: true},
+		{err: &APIError{Type: "AuthorizationError.USER_PERMISSION_DENIED"}, target: ErrAPIAuth, ok: true},
+		{err: &APIError{Type: "AuthorizationError.USER_PERMISSION_DENIED"}, target: ErrAPIQuery},
+		{err: &APIError{Type: "RateExceededError.RATE_EXCEEDED"}, target: ErrAPIRateLimit, ok: true},
+		{err: &APIError{Type: "QueryError.DATE_COLUMN_REQUIRES_DURING_CLAUSE"}, target: ErrAPIQuery, ok: true},
+		{err: &APIError{Type: "SelectorError.INVALID_FIELD_NAME"}, target: ErrAPIQuery, ok: true},
+		{err: &APIError{Type: "QuotaCheckError.ABSOLUTE_LIMIT"}, target: ErrAPIQuota, ok: true},
+		{err: &APIError{Type: "InternalApiError.UNEXPECTED_INTERNAL_API_ERROR"}, target: ErrAPIInternal, ok: true},
+		{err: &APIError{Type: "ReportDownloadError.ERROR_GETTING_RESPONSE_FROM_BACKEND"}, target: ErrAPIInternal, ok: true},
+		{err: &APIError{Type: "ReportDownloadError.ERROR_GETTING_RESPONSE_FROM_BACKEND"}, target: ErrAPIQuery},
+		{err: &APIError{Type: "QueryError.INVALID_VALUE"}, target: ErrBadNetwork},
+		{err: &APIError{StatusCode: http.StatusUnauthorized}, target: ErrAPIAuth, ok: true},
+		{err: &APIError{StatusCode: http.StatusForbidden}, target: ErrAPIAuth, ok: true},
+		{err: &APIError{StatusCode: http.StatusTooManyRequests}, target: ErrAPIRateLimit, ok: true},
+		{err: &APIError{StatusCode: http.StatusBadGateway}, target: ErrAPIInternal, ok: true},
+		{err: &APIError{StatusCode: http.StatusBadRequest}, target: ErrAPIQuery},
+		{err: &APIError{Type: "AuthorizationError.USER_PERMISSION_DENIED", StatusCode: http.StatusInternalServerError}, target: ErrAPIInternal},
+	}
+	for i, it := range isTests {
+		if ok := errors.Is(it.err, it.target); ok != it.ok {
+			t.Errorf("%d. Expected %t with %v as target, received %t", i, it.ok, it.target, ok)
+		}
+	}
+}
+
+// TestConnectionError_Unwrap tests the methods named Is and Unwrap on ConnectionError.
+func TestConnectionError_Unwrap(t *testing.T) {
+	err := wrapConnectionError(ErrBadNetwork, io.ErrUnexpectedEOF)
+	if exp := "ConnectionError.SERVICE_UNAVAILABLE (unexpected EOF)"; err.Error() != exp {
+		t.Errorf("Expected %s, received %v", exp, err)
+	}
+	if !errors.Is(err, ErrBadNetwork) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected %v and its cause with %v", ErrBadNetwork, err)
+	}
+	if errors.Is(err, ErrNoNetwork) {
+		t.Errorf("Expected no match with %v", ErrNoNetwork)
+	}
+	if errors.Unwrap(ErrBadNetwork) != nil {
+		t.Errorf("Expected no cause with %v", ErrBadNetwork)
+	}
+}
+
+// TestQueryError_Unwrap tests the methods named Is and Unwrap on QueryError.
+func TestQueryError_Unwrap(t *testing.T) {
+	cause := parser.NewError("SELECT FROM R", "unexpected token", "FROM", 7)
+	err := newSyntaxError(cause)
+
+	var pe *parser.Error
+	if !errors.As(err, &pe) || pe != cause {
+		t.Errorf("Expected the syntax error of the parser as cause, received %v", pe)
+	}
+	if !errors.Is(err, &QueryError{s: "UNEXPECTED_TOKEN"}) || errors.Is(err, ErrQuery) {
+		t.Errorf("Expected a match on the message only with %v", err)
+	}
+}
+
 // TestNewQueryError tests the method named NewQueryError.
 func TestNewQueryError(t *testing.T) {
 	err := NewQueryError("hello word")
@@ -62,3 +187,20 @@ func TestNewQueryError(t *testing.T) {
 		t.Fatalf("Unexpected error message: %v", err.Error())
 	}
 }
+
+// TestQueryError_Error tests the method named Error on QueryError.
+func TestQueryError_Error(t *testing.T) {
+	var errorTests = []struct {
+		err *QueryError
+		msg string
+	}{
+		{&QueryError{s: "MISSING"}, "QueryError.MISSING"},
+		{&QueryError{s: "MISSING_REPORT", Pos: 15}, "QueryError.MISSING_REPORT at position 15"},
+		{&QueryError{s: "UNEXPECTED_TOKEN", Trigger: "FORM", Pos: 11}, "QueryError.UNEXPECTED_TOKEN (FORM) at position 11"},
+	}
+	for i, et := range errorTests {
+		if msg := et.err.Error(); msg != et.msg {
+			t.Errorf("%d. Expected %s, received %s", i, et.msg, msg)
+		}
+	}
+}
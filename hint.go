@@ -0,0 +1,64 @@
+package awql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// List of the hints overriding the options of the connection for a query,
+// as in SELECT /*+ zero_impressions raw_enums */ CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT.
+// Prefixed by no_, they disable the option.
+const (
+	hintZeroImpressions = "zero_impressions"
+	hintRawEnumValues   = "raw_enums"
+	hintNegation        = "no_"
+	msgUnknownHint      = "unknown hint"
+)
+
+// optsCtxKey is the type of the context keys used to override the options of the connection.
+type optsCtxKey int
+
+const (
+	zeroImpressionsKey optsCtxKey = iota
+	rawEnumValuesKey
+)
+
+// ContextWithZeroImpressions returns a copy of the context in which the queries include or not
+// the rows without impression. It overrides the IncludeZeroImpressions option of the connection.
+func ContextWithZeroImpressions(ctx context.Context, include bool) context.Context {
+	return context.WithValue(ctx, zeroImpressionsKey, include)
+}
+
+// ContextWithRawEnumValues returns a copy of the context in which the queries return or not
+// the raw values of the enum fields. It overrides the UseRawEnumValues option of the connection.
+func ContextWithRawEnumValues(ctx context.Context, raw bool) context.Context {
+	return context.WithValue(ctx, rawEnumValuesKey, raw)
+}
+
+// queryOpts returns the options of the connection overridden by the context,
+// then by the hints of the statement parsed from the query q.
+// An unknown hint is returned as QueryError.
+func queryOpts(ctx context.Context, o *Opts, st *parser.SelectStmt, q string) (*Opts, error) {
+	c := *o
+	if v, ok := ctx.Value(zeroImpressionsKey).(bool); ok {
+		c.IncludeZeroImpressions = v
+	}
+	if v, ok := ctx.Value(rawEnumValuesKey).(bool); ok {
+		c.UseRawEnumValues = v
+	}
+	for _, h := range st.Hints {
+		name := strings.ToLower(h.Name)
+		on := !strings.HasPrefix(name, hintNegation)
+		switch strings.TrimPrefix(name, hintNegation) {
+		case hintZeroImpressions:
+			c.IncludeZeroImpressions = on
+		case hintRawEnumValues:
+			c.UseRawEnumValues = on
+		default:
+			return nil, newSyntaxError(parser.NewError(q, msgUnknownHint, h.Name, h.Offset))
+		}
+	}
+	return &c, nil
+}
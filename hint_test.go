@@ -0,0 +1,79 @@
+package awql_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// TestContextWithZeroImpressions tests the options of the connection overridden by the context and the hints.
+func TestContextWithZeroImpressions(t *testing.T) {
+	var hintTests = []struct {
+		ctx        context.Context
+		q          string
+		zero, enum string
+		err        string
+	}{
+		{ctx: context.Background(), q: "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT", zero: "true", enum: "false"},
+		{
+			ctx:  awql.ContextWithZeroImpressions(context.Background(), false),
+			q:    "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			zero: "false", enum: "false",
+		},
+		{
+			ctx:  awql.ContextWithRawEnumValues(context.Background(), true),
+			q:    "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			zero: "true", enum: "true",
+		},
+		{
+			ctx:  context.Background(),
+			q:    "SELECT /*+ NO_ZERO_IMPRESSIONS raw_enums */ CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			zero: "false", enum: "true",
+		},
+		{
+			ctx:  awql.ContextWithZeroImpressions(context.Background(), false),
+			q:    "SELECT /*+ zero_impressions */ CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			zero: "true", enum: "false",
+		},
+		{
+			ctx: context.Background(),
+			q:   "SELECT /*+ zero */ CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			err: "QueryError.UNKNOWN_HINT (zero) at position 12",
+		},
+	}
+	for i, ht := range hintTests {
+		var rqs []*http.Request
+		db := sql.OpenDB(newConnector(
+			t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", SupportsZeroImpressions: true},
+			awql.WithHTTPClient(newClient(http.StatusOK, "Campaign\nRv\n", &rqs)),
+		))
+		var name string
+		err := db.QueryRowContext(ht.ctx, ht.q).Scan(&name)
+		_ = db.Close()
+		if ht.err != "" {
+			if err == nil || err.Error() != ht.err {
+				t.Errorf("%d. Expected %s as error, received %v", i, ht.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if len(rqs) != 1 {
+			t.Fatalf("%d. Expected one request, received %d", i, len(rqs))
+		}
+		if h := rqs[0].Header.Get("includeZeroImpressions"); h != ht.zero {
+			t.Errorf("%d. Expected %s as zero impressions, received %s", i, ht.zero, h)
+		}
+		if h := rqs[0].Header.Get("useRawEnumValues"); h != ht.enum {
+			t.Errorf("%d. Expected %s as raw enum values, received %s", i, ht.enum, h)
+		}
+		_ = rqs[0].ParseForm()
+		if q := rqs[0].PostForm.Get("__rdquery"); q != "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT" {
+			t.Errorf("%d. Expected the query without its hints, received %s", i, q)
+		}
+	}
+}
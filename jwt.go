@@ -0,0 +1,171 @@
+package awql
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	jwtGrantType  = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	jwtScope      = "https://www.googleapis.com/auth/adwords"
+	jwtExpiration = time.Hour
+)
+
+// serviceAccountKey represents the JSON key of a Google service account.
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// readServiceAccountKey returns the JSON key of the service account,
+// given inline or as the path of the key file.
+func readServiceAccountKey(s string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		return []byte(s), nil
+	}
+	d, err := ioutil.ReadFile(s)
+	if err != nil {
+		return nil, ErrServiceAccount
+	}
+	return d, nil
+}
+
+// ServiceAccountTokenSource returns a TokenSource retrieving a new access token
+// with the JSON key of the service account on each call, by signing locally a JWT assertion.
+// With G Suite domain-wide delegation, the user is the email address of the impersonated user.
+// It is usually wrapped by ReuseTokenSource or SharedTokenSource.
+// If the HTTP client is nil, http.DefaultClient is used.
+func ServiceAccountTokenSource(client *http.Client, key []byte, user string) (TokenSource, error) {
+	return newServiceAccountTokenSource(client, key, user, DefaultRetryPolicy)
+}
+
+func newServiceAccountTokenSource(client *http.Client, key []byte, user string, retry RetryPolicy) (*jwtTokenSource, error) {
+	var k serviceAccountKey
+	if err := json.Unmarshal(key, &k); err != nil {
+		return nil, ErrServiceAccount
+	}
+	if k.Type != "service_account" || k.ClientEmail == "" {
+		return nil, ErrServiceAccount
+	}
+	pk, err := parsePrivateKey(k.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if k.TokenURI == "" {
+		k.TokenURI = tokenURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &jwtTokenSource{client: client, key: k, pk: pk, user: user, retry: retry}, nil
+}
+
+// parsePrivateKey returns the RSA private key encoded in PEM, with the PKCS #8 or PKCS #1 format.
+func parsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	b, _ := pem.Decode([]byte(s))
+	if b == nil {
+		return nil, ErrServiceAccount
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(b.Bytes); err == nil {
+		pk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrServiceAccount
+		}
+		return pk, nil
+	}
+	pk, err := x509.ParsePKCS1PrivateKey(b.Bytes)
+	if err != nil {
+		return nil, ErrServiceAccount
+	}
+	return pk, nil
+}
+
+type jwtTokenSource struct {
+	client *http.Client
+	key    serviceAccountKey
+	pk     *rsa.PrivateKey
+	user   string
+	retry  RetryPolicy
+}
+
+// cacheKey returns the key identifying the service account and the impersonated user.
+func (s *jwtTokenSource) cacheKey() string {
+	return tokenKey("jwt", s.key.ClientEmail, s.key.PrivateKeyID, s.user)
+}
+
+// Token implements the TokenSource interface.
+// The token request, including its retries, can not exceed the tokenTimeout duration.
+func (s *jwtTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	a, err := s.assertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, tokenTimeout)
+	defer cancel()
+
+	var d io.ReadCloser
+	err = s.retry.do(ctx, func() (err error) {
+		d, err = postToken(ctx, s.client, s.key.TokenURI, url.Values{
+			"grant_type": {jwtGrantType},
+			"assertion":  {a},
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retrieveToken(d)
+}
+
+// assertion returns the JWT signed with the private key of the service account, issued at this time.
+// @see https://developers.google.com/identity/protocols/oauth2/service-account#authorizingrequests
+func (s *jwtTokenSource) assertion(now time.Time) (string, error) {
+	head, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": s.key.PrivateKeyID,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims := map[string]interface{}{
+		"iss":   s.key.ClientEmail,
+		"scope": jwtScope,
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(jwtExpiration).Unix(),
+	}
+	if s.user != "" {
+		// Domain-wide delegation.
+		claims["sub"] = s.user
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	msg := enc.EncodeToString(head) + "." + enc.EncodeToString(body)
+
+	h := sha256.Sum256([]byte(msg))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.pk, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+	return msg + "." + enc.EncodeToString(sig), nil
+}
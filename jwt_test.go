@@ -0,0 +1,207 @@
+package awql_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// newServiceAccountKey returns the JSON key of a service account and its private key.
+func newServiceAccountKey(t *testing.T) ([]byte, *rsa.PrivateKey) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	key, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "awql@rv.iam.gserviceaccount.com",
+		"private_key_id": "k3y1d",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	return key, pk
+}
+
+// TestServiceAccountTokenSource tests the function named ServiceAccountTokenSource.
+func TestServiceAccountTokenSource(t *testing.T) {
+	key, pk := newServiceAccountKey(t)
+	for i, k := range []string{"", "{}", `{"type":"authorized_user"}`, `{"type":"service_account","client_email":"rv"}`} {
+		if _, err := awql.ServiceAccountTokenSource(nil, []byte(k), ""); err != awql.ErrServiceAccount {
+			t.Errorf("%d. Expected %v as error, received %v", i, awql.ErrServiceAccount, err)
+		}
+	}
+
+	var rqs []*http.Request
+	ts, err := awql.ServiceAccountTokenSource(
+		newClient(http.StatusOK, `{"access_token":"ya29.A","token_type":"Bearer","expires_in":3600}`, &rqs),
+		key, "rv@example.com",
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	tk, err := ts.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if tk.String() != "Bearer ya29.A" {
+		t.Errorf("Expected the access token of the response, received %v", tk)
+	}
+	if len(rqs) != 1 {
+		t.Fatalf("Expected one token request, received %d", len(rqs))
+	}
+	_ = rqs[0].ParseForm()
+	if g := rqs[0].PostForm.Get("grant_type"); g != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Errorf("Expected the JWT bearer grant type, received %v", g)
+	}
+
+	// Checks the signature and the claims of the assertion.
+	parts := strings.Split(rqs[0].PostForm.Get("assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT as assertion, received %v", parts)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&pk.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+		t.Errorf("Expected a valid signature, received %v", err)
+	}
+	var claims map[string]interface{}
+	body, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(body, &claims); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	for k, v := range map[string]string{
+		"iss":   "awql@rv.iam.gserviceaccount.com",
+		"sub":   "rv@example.com",
+		"scope": "https://www.googleapis.com/auth/adwords",
+	} {
+		if claims[k] != v {
+			t.Errorf("Expected %s as %s claim, received %v", v, k, claims[k])
+		}
+	}
+}
+
+// TestDsn_ServiceAccount tests the authentication with the service account of the data source name.
+func TestDsn_ServiceAccount(t *testing.T) {
+	key, _ := newServiceAccountKey(t)
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key.json")
+	if err := ioutil.WriteFile(path, key, 0600); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+
+	// The access token of the service account is shared by the connections.
+	var accountTests = []struct {
+		key      string
+		err      error
+		requests int
+	}{
+		{key: filepath.Join(dir, "missing.json"), err: awql.ErrServiceAccount},
+		{key: path, requests: 2},
+		{key: string(key), requests: 1},
+	}
+	for i, at := range accountTests {
+		var (
+			n      int
+			header string
+		)
+		client := &http.Client{
+			Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+				n++
+				body := "Account\nRv\n"
+				if rq.URL.Host == "accounts.google.com" {
+					body = `{"access_token":"ya29.A","token_type":"Bearer","expires_in":3600}`
+				} else {
+					header = rq.Header.Get("Authorization")
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader(body)),
+					Request:    rq,
+				}, nil
+			}),
+		}
+		dsn := &awql.Dsn{
+			AdwordsID:        "123-456-7890",
+			DeveloperToken:   "dEve1op3er7okeN",
+			ServiceAccount:   at.key,
+			ImpersonatedUser: "rv@example.com",
+		}
+		c, err := awql.NewConnector(dsn, awql.WithHTTPClient(client))
+		if err == nil {
+			db := sql.OpenDB(c)
+			var name string
+			err = db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name)
+			_ = db.Close()
+		}
+		if err != at.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, at.err, err)
+		}
+		if err == nil && (n != at.requests || header != "Bearer ya29.A") {
+			t.Errorf("%d. Expected the token of the service account, received %s after %d requests", i, header, n)
+		}
+	}
+}
+
+// TestDsn_ServiceAccount_Once tests that the key file of the service account is only read by NewConnector.
+func TestDsn_ServiceAccount_Once(t *testing.T) {
+	key, _ := newServiceAccountKey(t)
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key.json")
+	if err := ioutil.WriteFile(path, key, 0600); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	client := &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			body := "Account\nRv\n"
+			if rq.URL.Host == "accounts.google.com" {
+				body = `{"access_token":"ya29.B","token_type":"Bearer","expires_in":3600}`
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Request: rq}, nil
+		}),
+	}
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", ServiceAccount: path}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(client)))
+	defer db.Close()
+	// Each query dials a new connection.
+	db.SetMaxIdleConns(0)
+
+	// The key file is deleted once the connector created.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	for k := 0; k < 2; k++ {
+		var name string
+		if err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name); err != nil {
+			t.Errorf("%d. Expected no error with a new connection, received %v", k, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// microsPerUnit is the number of micros in one unit of currency.
+const microsPerUnit = 1000000
+
+// ToMicros converts an amount of money in its currency unit to micros, as expected by the Money fields.
+// The amount is rounded to the nearest micro.
+func ToMicros(amount float64) int64 {
+	return int64(math.Round(amount * microsPerUnit))
+}
+
+// FromMicros converts an amount of money in micros, as returned by the Money fields, to its currency unit.
+func FromMicros(micros int64) float64 {
+	return float64(micros) / microsPerUnit
+}
+
+// literal returns the value as literal of an AWQL query:
+// a string or a slice of bytes as double-quoted string, a number with its full precision,
+// a boolean as TRUE or FALSE and a time as date YYYYMMDD.
+// A slice is returned as list of values, as [1, 2].
+// Any other value, nil included, is returned as ErrQueryValue.
+func literal(rv driver.Value) (string, error) {
+	switch v := rv.(type) {
+	case nil:
+		return "", ErrQueryValue
+	case string:
+		return quote(v), nil
+	case []byte:
+		return quote(string(v)), nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case time.Time:
+		return v.Format(parser.DateLayout), nil
+	case []driver.Value:
+		if len(v) == 0 {
+			// Empty list.
+			return "", ErrQueryBinding
+		}
+		s := make([]string, len(v))
+		for k, e := range v {
+			if _, ok := e.([]driver.Value); ok {
+				// Nested list.
+				return "", ErrQueryBinding
+			}
+			var err error
+			if s[k], err = literal(e); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(s, ", ") + "]", nil
+	}
+	switch v := reflect.ValueOf(rv); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return formatFloat(v.Float(), 32)
+	case reflect.Float64:
+		return formatFloat(v.Float(), 64)
+	}
+	return "", ErrQueryValue
+}
+
+// formatFloat returns the float with the minimal number of digits to represent it exactly,
+// without exponent. NaN and infinite values are returned as ErrQueryValue.
+func formatFloat(f float64, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", ErrQueryValue
+	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize), nil
+}
+
+// quote returns the string enclosed by double quotes, its backslashes and double quotes being escaped.
+// Unlike Go, any other character is kept as it.
+func quote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' || s[i] == '"' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// TestLiteral tests the encoding of the values as AWQL literals.
+func TestLiteral(t *testing.T) {
+	var literalTests = []struct {
+		in  driver.Value
+		out string
+		err error
+	}{
+		{in: "rv", out: `"rv"`},
+		{in: `é "a\b"`, out: `"é \"a\\b\""`},
+		{in: []byte("rv"), out: `"rv"`},
+		{in: true, out: "TRUE"},
+		{in: false, out: "FALSE"},
+		{in: 12, out: "12"},
+		{in: int64(-12), out: "-12"},
+		{in: uint8(7), out: "7"},
+		{in: 12.3, out: "12.3"},
+		{in: float32(0.1), out: "0.1"},
+		{in: 0.123456789, out: "0.123456789"},
+		{in: 1e21, out: "1000000000000000000000"},
+		{in: time.Date(2017, time.March, 9, 12, 0, 0, 0, time.UTC), out: "20170309"},
+		{in: []driver.Value{int64(1), "a"}, out: `[1, "a"]`},
+		{in: []driver.Value{}, err: ErrQueryBinding},
+		{in: []driver.Value{nil}, err: ErrQueryValue},
+		{in: nil, err: ErrQueryValue},
+		{in: math.NaN(), err: ErrQueryValue},
+		{in: struct{}{}, err: ErrQueryValue},
+	}
+	for i, lt := range literalTests {
+		out, err := literal(lt.in)
+		if err != lt.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, lt.err, err)
+		} else if out != lt.out {
+			t.Errorf("%d. Expected %s as literal, received %s", i, lt.out, out)
+		}
+	}
+}
+
+// TestQuote tests that a quoted string is unquoted by the parser as it.
+func TestQuote(t *testing.T) {
+	for i, s := range []string{"", "rv", `"`, `\`, `a\"b'c`, "é?"} {
+		if out := parser.Unquote(quote(s)); out != s {
+			t.Errorf("%d. Expected %s after unquoting, received %s", i, s, out)
+		}
+	}
+}
+
+// TestToMicros tests the conversion of money amounts to micros and back.
+func TestToMicros(t *testing.T) {
+	var microsTests = []struct {
+		amount float64
+		micros int64
+	}{
+		{0, 0},
+		{1, 1000000},
+		{12.34, 12340000},
+		{0.0000015, 2},
+		{-3.5, -3500000},
+	}
+	for i, mt := range microsTests {
+		if m := ToMicros(mt.amount); m != mt.micros {
+			t.Errorf("%d. Expected %d micros, received %d", i, mt.micros, m)
+		}
+	}
+	if a := FromMicros(12340000); a != 12.34 {
+		t.Errorf("Expected 12.34 as amount, received %v", a)
+	}
+}
@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Statement represents a statement parsed from a query.
+type Statement interface {
+	// Params returns the placeholders of the statement, in order of appearance.
+	Params() []*Placeholder
+	// String returns the statement as AWQL query.
+	String() string
+}
+
+// Ident represents an identifier, a field or a report name.
+type Ident struct {
+	Name   string
+	Offset int
+}
+
+// Pos returns the offset of the identifier in the query.
+func (i *Ident) Pos() int {
+	return i.Offset
+}
+
+// String returns the name of the identifier.
+func (i *Ident) String() string {
+	return i.Name
+}
+
+// Column represents a field or an aggregate function of a field, with an optional alias.
+// The field of the function COUNT(*) is named "*".
+type Column struct {
+	Field  *Ident
+	Func   string
+	Alias  *Ident
+	Offset int
+}
+
+// Pos returns the offset of the column in the query.
+func (c *Column) Pos() int {
+	return c.Offset
+}
+
+// Expr returns the column as AWQL expression, without its alias.
+func (c *Column) Expr() string {
+	if c.Func == "" {
+		return c.Field.String()
+	}
+	return c.Func + "(" + c.Field.String() + ")"
+}
+
+// Name returns the alias of the column or, by default, its expression.
+func (c *Column) Name() string {
+	if c.Alias != nil {
+		return c.Alias.Name
+	}
+	return c.Expr()
+}
+
+// String returns the column as AWQL expression, with its alias.
+func (c *Column) String() string {
+	if c.Alias == nil {
+		return c.Expr()
+	}
+	return c.Expr() + " AS " + c.Alias.String()
+}
+
+// Value represents the value of a condition.
+type Value interface {
+	// Pos returns the offset of the value in the query.
+	Pos() int
+	// String returns the value as AWQL literal.
+	String() string
+}
+
+// Literal represents a number, a string or an enum value.
+type Literal struct {
+	Kind   Kind
+	Raw    string
+	Offset int
+}
+
+// Pos returns the offset of the literal in the query.
+func (l *Literal) Pos() int {
+	return l.Offset
+}
+
+// String returns the literal as written in the query.
+func (l *Literal) String() string {
+	return l.Raw
+}
+
+// Text returns the value of the literal, without quotes for a string.
+func (l *Literal) Text() string {
+	if l.Kind == STRING {
+		return Unquote(l.Raw)
+	}
+	return l.Raw
+}
+
+// List represents a list of values, as [1, 2, 3].
+type List struct {
+	Values []Value
+	Offset int
+}
+
+// Pos returns the offset of the list in the query.
+func (l *List) Pos() int {
+	return l.Offset
+}
+
+// String returns the list as AWQL list.
+func (l *List) String() string {
+	s := make([]string, len(l.Values))
+	for k, v := range l.Values {
+		s[k] = v.String()
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+// Placeholder represents a parameter of the query.
+// Index is its position among the parameters, starting at 0.
+// Name is the named parameter as written, :name or @name, empty for ?.
+type Placeholder struct {
+	Index  int
+	Name   string
+	Offset int
+}
+
+// Pos returns the offset of the placeholder in the query.
+func (p *Placeholder) Pos() int {
+	return p.Offset
+}
+
+// String returns the placeholder as written in the query.
+func (p *Placeholder) String() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "?"
+}
+
+// Condition represents a predicate of the WHERE or HAVING clause.
+// Only the predicates of the HAVING clause can apply on aggregate functions.
+type Condition struct {
+	Field    *Column
+	Operator string
+	Value    Value
+}
+
+// String returns the condition as AWQL predicate.
+func (c *Condition) String() string {
+	return c.Field.Expr() + " " + c.Operator + " " + c.Value.String()
+}
+
+// During represents the DURING clause, with a predefined date range
+// or a custom one with its start and end dates.
+type During struct {
+	Range      *Ident
+	Start, End Value
+	Offset     int
+}
+
+// Pos returns the offset of the clause in the query.
+func (d *During) Pos() int {
+	return d.Offset
+}
+
+// String returns the date range of the DURING clause.
+func (d *During) String() string {
+	if d.Range != nil {
+		return d.Range.String()
+	}
+	if d.End == nil {
+		return d.Start.String()
+	}
+	return d.Start.String() + "," + d.End.String()
+}
+
+// Order represents a sort criterion of the ORDER BY clause.
+type Order struct {
+	Field *Column
+	Desc  bool
+}
+
+// String returns the sort criterion as AWQL.
+func (o *Order) String() string {
+	if o.Desc {
+		return o.Field.Expr() + " DESC"
+	}
+	return o.Field.Expr() + " ASC"
+}
+
+// Limit represents the LIMIT clause, with the index of the first row to return
+// and the maximum number of rows to return.
+type Limit struct {
+	Start, Count int
+	Offset       int
+}
+
+// Pos returns the offset of the clause in the query.
+func (l *Limit) Pos() int {
+	return l.Offset
+}
+
+// String returns the LIMIT clause as AWQL.
+func (l *Limit) String() string {
+	if l.Start == 0 {
+		return strconv.Itoa(l.Count)
+	}
+	return strconv.Itoa(l.Start) + ", " + strconv.Itoa(l.Count)
+}
+
+// SelectStmt represents a SELECT statement.
+// Hints lists the names of its hint comments, as /*+ zero_impressions */.
+type SelectStmt struct {
+	Hints   []*Ident
+	Fields  []*Column
+	From    *Ident
+	Where   []*Condition
+	During  *During
+	GroupBy []*Ident
+	Having  []*Condition
+	OrderBy []*Order
+	Limit   *Limit
+	params  []*Placeholder
+}
+
+// Aggregated returns true if the statement groups rows or uses aggregate functions.
+func (s *SelectStmt) Aggregated() bool {
+	if len(s.GroupBy) > 0 || len(s.Having) > 0 {
+		return true
+	}
+	for _, c := range s.Fields {
+		if c.Func != "" {
+			return true
+		}
+	}
+	for _, o := range s.OrderBy {
+		if o.Field.Func != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Params returns the placeholders of the statement, in order of appearance.
+func (s *SelectStmt) Params() []*Placeholder {
+	return s.params
+}
+
+// FieldNames returns the names of the fields of the selected columns.
+func (s *SelectStmt) FieldNames() []string {
+	names := make([]string, len(s.Fields))
+	for k, c := range s.Fields {
+		names[k] = c.Field.Name
+	}
+	return names
+}
+
+// String returns the statement as AWQL query, without its hints.
+func (s *SelectStmt) String() string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	for k, f := range s.Fields {
+		if k > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.String())
+	}
+	b.WriteString(" FROM ")
+	b.WriteString(s.From.String())
+	for k, c := range s.Where {
+		if k == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(c.String())
+	}
+	if s.During != nil {
+		b.WriteString(" DURING ")
+		b.WriteString(s.During.String())
+	}
+	for k, f := range s.GroupBy {
+		if k == 0 {
+			b.WriteString(" GROUP BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.String())
+	}
+	for k, c := range s.Having {
+		if k == 0 {
+			b.WriteString(" HAVING ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(c.String())
+	}
+	for k, o := range s.OrderBy {
+		if k == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(o.String())
+	}
+	if s.Limit != nil {
+		b.WriteString(" LIMIT ")
+		b.WriteString(s.Limit.String())
+	}
+	return b.String()
+}
+
+// ShowTablesStmt represents a SHOW TABLES statement, listing the reports.
+// Like is the optional pattern of the names of the reports.
+type ShowTablesStmt struct {
+	Like   Value
+	params []*Placeholder
+}
+
+// Params returns the placeholders of the statement, in order of appearance.
+func (s *ShowTablesStmt) Params() []*Placeholder {
+	return s.params
+}
+
+// String returns the statement as query.
+func (s *ShowTablesStmt) String() string {
+	if s.Like == nil {
+		return "SHOW TABLES"
+	}
+	return "SHOW TABLES LIKE " + s.Like.String()
+}
+
+// DescribeStmt represents a DESCRIBE or a SHOW [FULL] COLUMNS statement, listing the fields of a report.
+// Like is the optional pattern of the names of the fields.
+type DescribeStmt struct {
+	Table  *Ident
+	Full   bool
+	Like   Value
+	params []*Placeholder
+}
+
+// Params returns the placeholders of the statement, in order of appearance.
+func (s *DescribeStmt) Params() []*Placeholder {
+	return s.params
+}
+
+// String returns the statement as query, a DESCRIBE statement being written as SHOW COLUMNS.
+func (s *DescribeStmt) String() string {
+	var b strings.Builder
+	b.WriteString("SHOW ")
+	if s.Full {
+		b.WriteString("FULL ")
+	}
+	b.WriteString("COLUMNS FROM ")
+	b.WriteString(s.Table.String())
+	if s.Like != nil {
+		b.WriteString(" LIKE ")
+		b.WriteString(s.Like.String())
+	}
+	return b.String()
+}
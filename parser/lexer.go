@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind represents the kind of a lexical token.
+type Kind int
+
+// List of token kinds.
+const (
+	ILLEGAL Kind = iota
+	EOF
+	IDENT
+	NUMBER
+	STRING
+	PLACEHOLDER
+	COMMA
+	SEMICOLON
+	LPAREN
+	RPAREN
+	LBRACKET
+	RBRACKET
+	STAR
+	EQ
+	NEQ
+	LT
+	LTE
+	GT
+	GTE
+	HINT
+)
+
+var kinds = [...]string{
+	ILLEGAL:     "ILLEGAL",
+	EOF:         "EOF",
+	IDENT:       "IDENT",
+	NUMBER:      "NUMBER",
+	STRING:      "STRING",
+	PLACEHOLDER: "?",
+	COMMA:       ",",
+	SEMICOLON:   ";",
+	LPAREN:      "(",
+	RPAREN:      ")",
+	LBRACKET:    "[",
+	RBRACKET:    "]",
+	STAR:        "*",
+	EQ:          "=",
+	NEQ:         "!=",
+	LT:          "<",
+	LTE:         "<=",
+	GT:          ">",
+	GTE:         ">=",
+	HINT:        "HINT",
+}
+
+// String returns a representation of the kind of token.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kinds) {
+		return kinds[ILLEGAL]
+	}
+	return kinds[k]
+}
+
+// Token represents a lexical token of a query.
+// Offset is the position in bytes of its first character in the query.
+type Token struct {
+	Kind   Kind
+	Lit    string
+	Offset int
+}
+
+// Keyword returns true if the token is the given keyword, whatever its case.
+func (t Token) Keyword(kw string) bool {
+	return t.Kind == IDENT && strings.EqualFold(t.Lit, kw)
+}
+
+// String returns the literal of the token.
+func (t Token) String() string {
+	if t.Kind == EOF {
+		return "EOF"
+	}
+	return t.Lit
+}
+
+// Lexer splits a query into tokens.
+type Lexer struct {
+	src string
+	pos int
+}
+
+// NewLexer returns a new instance of Lexer to tokenize the given query.
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src}
+}
+
+// Next returns the next token of the query.
+// At the end of the query, it returns a token of kind EOF.
+// The comments are skipped, except the hint comments starting with "/*+".
+func (l *Lexer) Next() Token {
+	l.skipSpaces()
+	for strings.HasPrefix(l.src[l.pos:], "/*") {
+		start := l.pos
+		end := strings.Index(l.src[start+2:], "*/")
+		if end < 0 {
+			// Unterminated comment.
+			l.pos = len(l.src)
+			return l.token(ILLEGAL, start)
+		}
+		l.pos = start + 2 + end + 2
+		if strings.HasPrefix(l.src[start:], "/*+") {
+			return l.token(HINT, start)
+		}
+		l.skipSpaces()
+	}
+	if l.pos >= len(l.src) {
+		return Token{Kind: EOF, Offset: len(l.src)}
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case isLetter(c):
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return l.token(IDENT, start)
+	case isDigit(c), (c == '-' || c == '.') && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		return l.number(start)
+	case c == '"', c == '\'':
+		return l.string(start)
+	case (c == ':' || c == '@') && l.pos+1 < len(l.src) && isLetter(l.src[l.pos+1]):
+		// Named parameter.
+		l.pos++
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return l.token(PLACEHOLDER, start)
+	}
+	l.pos++
+	switch c {
+	case '?':
+		return l.token(PLACEHOLDER, start)
+	case ',':
+		return l.token(COMMA, start)
+	case ';':
+		return l.token(SEMICOLON, start)
+	case '(':
+		return l.token(LPAREN, start)
+	case ')':
+		return l.token(RPAREN, start)
+	case '[':
+		return l.token(LBRACKET, start)
+	case ']':
+		return l.token(RBRACKET, start)
+	case '*':
+		return l.token(STAR, start)
+	case '=':
+		return l.token(EQ, start)
+	case '!':
+		if l.accept('=') {
+			return l.token(NEQ, start)
+		}
+	case '<':
+		if l.accept('=') {
+			return l.token(LTE, start)
+		}
+		return l.token(LT, start)
+	case '>':
+		if l.accept('=') {
+			return l.token(GTE, start)
+		}
+		return l.token(GT, start)
+	default:
+		// Keeps the whole character as illegal.
+		_, size := utf8.DecodeRuneInString(l.src[start:])
+		l.pos = start + size
+	}
+	return l.token(ILLEGAL, start)
+}
+
+// accept consumes the next character if it is the given one.
+func (l *Lexer) accept(c byte) bool {
+	if l.pos < len(l.src) && l.src[l.pos] == c {
+		l.pos++
+		return true
+	}
+	return false
+}
+
+// number scans an integer or a decimal number, optionally negative.
+func (l *Lexer) number(start int) Token {
+	l.accept('-')
+	var dot bool
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '.' && !dot {
+			dot = true
+		} else if !isDigit(c) {
+			break
+		}
+		l.pos++
+	}
+	if l.pos < len(l.src) && isLetter(l.src[l.pos]) {
+		// Number followed by letters, as 12ab.
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return l.token(ILLEGAL, start)
+	}
+	return l.token(NUMBER, start)
+}
+
+// string scans a string literal enclosed by single or double quotes.
+// The backslash escapes the next character.
+// An unterminated string is returned as illegal token.
+func (l *Lexer) string(start int) Token {
+	quote := l.src[l.pos]
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case quote:
+			l.pos++
+			return l.token(STRING, start)
+		}
+		l.pos++
+	}
+	l.pos = len(l.src)
+	return l.token(ILLEGAL, start)
+}
+
+// skipSpaces moves the cursor after the white spaces.
+func (l *Lexer) skipSpaces() {
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		l.pos += size
+	}
+}
+
+// token returns the token of this kind starting at the given offset.
+func (l *Lexer) token(k Kind, start int) Token {
+	return Token{Kind: k, Lit: l.src[start:l.pos], Offset: start}
+}
+
+// Unquote returns the value of a string literal, without its quotes and escape characters.
+// Any other literal is returned as it.
+func Unquote(lit string) string {
+	if len(lit) < 2 || (lit[0] != '"' && lit[0] != '\'') || lit[len(lit)-1] != lit[0] {
+		return lit
+	}
+	lit = lit[1 : len(lit)-1]
+	if strings.IndexByte(lit, '\\') < 0 {
+		return lit
+	}
+	var b strings.Builder
+	for i := 0; i < len(lit); i++ {
+		if lit[i] == '\\' && i+1 < len(lit) {
+			i++
+		}
+		b.WriteByte(lit[i])
+	}
+	return b.String()
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,121 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// TestLexer_Next tests the method Next on the Lexer struct.
+func TestLexer_Next(t *testing.T) {
+	var lexerTests = []struct {
+		in  string
+		out []parser.Token
+	}{
+		{in: "", out: []parser.Token{{Kind: parser.EOF}}},
+		{
+			in: "SELECT Id FROM R WHERE Name = 'a?b' AND Cost >= ?;",
+			out: []parser.Token{
+				{Kind: parser.IDENT, Lit: "SELECT", Offset: 0},
+				{Kind: parser.IDENT, Lit: "Id", Offset: 7},
+				{Kind: parser.IDENT, Lit: "FROM", Offset: 10},
+				{Kind: parser.IDENT, Lit: "R", Offset: 15},
+				{Kind: parser.IDENT, Lit: "WHERE", Offset: 17},
+				{Kind: parser.IDENT, Lit: "Name", Offset: 23},
+				{Kind: parser.EQ, Lit: "=", Offset: 28},
+				{Kind: parser.STRING, Lit: "'a?b'", Offset: 30},
+				{Kind: parser.IDENT, Lit: "AND", Offset: 36},
+				{Kind: parser.IDENT, Lit: "Cost", Offset: 40},
+				{Kind: parser.GTE, Lit: ">=", Offset: 45},
+				{Kind: parser.PLACEHOLDER, Lit: "?", Offset: 48},
+				{Kind: parser.SEMICOLON, Lit: ";", Offset: 49},
+				{Kind: parser.EOF, Offset: 50},
+			},
+		},
+		{
+			in: `[-1.5, "a\"b"] != < <= >`,
+			out: []parser.Token{
+				{Kind: parser.LBRACKET, Lit: "[", Offset: 0},
+				{Kind: parser.NUMBER, Lit: "-1.5", Offset: 1},
+				{Kind: parser.COMMA, Lit: ",", Offset: 5},
+				{Kind: parser.STRING, Lit: `"a\"b"`, Offset: 7},
+				{Kind: parser.RBRACKET, Lit: "]", Offset: 13},
+				{Kind: parser.NEQ, Lit: "!=", Offset: 15},
+				{Kind: parser.LT, Lit: "<", Offset: 18},
+				{Kind: parser.LTE, Lit: "<=", Offset: 20},
+				{Kind: parser.GT, Lit: ">", Offset: 23},
+				{Kind: parser.EOF, Offset: 24},
+			},
+		},
+		{
+			in: `12ab "oops`,
+			out: []parser.Token{
+				{Kind: parser.ILLEGAL, Lit: "12ab", Offset: 0},
+				{Kind: parser.ILLEGAL, Lit: `"oops`, Offset: 5},
+				{Kind: parser.EOF, Offset: 10},
+			},
+		},
+		{
+			in: "SELECT /* comment */ /*+ raw_enums */ Id /* oops",
+			out: []parser.Token{
+				{Kind: parser.IDENT, Lit: "SELECT", Offset: 0},
+				{Kind: parser.HINT, Lit: "/*+ raw_enums */", Offset: 21},
+				{Kind: parser.IDENT, Lit: "Id", Offset: 38},
+				{Kind: parser.ILLEGAL, Lit: "/* oops", Offset: 41},
+				{Kind: parser.EOF, Offset: 48},
+			},
+		},
+		{
+			in: "Id = :id AND Name IN @names : @1",
+			out: []parser.Token{
+				{Kind: parser.IDENT, Lit: "Id", Offset: 0},
+				{Kind: parser.EQ, Lit: "=", Offset: 3},
+				{Kind: parser.PLACEHOLDER, Lit: ":id", Offset: 5},
+				{Kind: parser.IDENT, Lit: "AND", Offset: 9},
+				{Kind: parser.IDENT, Lit: "Name", Offset: 13},
+				{Kind: parser.IDENT, Lit: "IN", Offset: 18},
+				{Kind: parser.PLACEHOLDER, Lit: "@names", Offset: 21},
+				{Kind: parser.ILLEGAL, Lit: ":", Offset: 28},
+				{Kind: parser.ILLEGAL, Lit: "@", Offset: 30},
+				{Kind: parser.NUMBER, Lit: "1", Offset: 31},
+				{Kind: parser.EOF, Offset: 32},
+			},
+		},
+		{
+			in: "é!",
+			out: []parser.Token{
+				{Kind: parser.ILLEGAL, Lit: "é", Offset: 0},
+				{Kind: parser.ILLEGAL, Lit: "!", Offset: 2},
+				{Kind: parser.EOF, Offset: 3},
+			},
+		},
+	}
+	for i, lt := range lexerTests {
+		lx := parser.NewLexer(lt.in)
+		for j, exp := range lt.out {
+			if tok := lx.Next(); tok != exp {
+				t.Errorf("%d.%d. Expected %#v, received %#v", i, j, exp, tok)
+			}
+		}
+	}
+}
+
+// TestUnquote tests the function named Unquote.
+func TestUnquote(t *testing.T) {
+	var unquoteTests = []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"rv", "rv"},
+		{`"rv"`, "rv"},
+		{`'rv'`, "rv"},
+		{`"r\"v"`, `r"v`},
+		{`'r\'v\\'`, `r'v\`},
+		{`"rv'`, `"rv'`},
+	}
+	for i, ut := range unquoteTests {
+		if out := parser.Unquote(ut.in); out != ut.out {
+			t.Errorf("%d. Expected %s, received %s", i, ut.out, out)
+		}
+	}
+}
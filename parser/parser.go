@@ -0,0 +1,672 @@
+// Package parser tokenizes and parses AWQL queries.
+//
+// The AdWords Query Language is a subset of SQL:
+//
+//	SELECT Field[, Field]* FROM Report
+//	[WHERE Condition[ AND Condition]*]
+//	[DURING DateRange]
+//
+// As the driver runs some clauses itself, the parser also accepts aliases,
+// aggregate functions and the following SQL clauses:
+//
+//	SELECT [/*+ Hint[ Hint]* */] Column [AS Alias][, Column [AS Alias]]* FROM Report
+//	[WHERE Condition[ AND Condition]*]
+//	[DURING DateRange]
+//	[GROUP BY Field[, Field]*]
+//	[HAVING Condition[ AND Condition]*]
+//	[ORDER BY Column [ASC|DESC][, Column [ASC|DESC]]*]
+//	[LIMIT [Start,] Count]
+//
+// A column is a field or one of the aggregate functions AVG, COUNT, MAX, MIN
+// and SUM applied on a field, as SUM(Cost) or COUNT(*).
+//
+// Any value of a condition or of the DURING clause can be replaced by
+// the placeholder ? or by a named parameter, as :name or @name, to be bound later.
+//
+// To describe the reports, the parser also accepts the following statements:
+//
+//	SHOW TABLES [LIKE Pattern]
+//	SHOW [FULL] COLUMNS FROM Report [LIKE Pattern]
+//	DESCRIBE Report
+//
+// The comments /* ... */ are ignored, except the hint comments following
+// the SELECT keyword, whose names are given to the driver.
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error represents a syntax error.
+// Pos is the position in characters of the offending token, starting at 1.
+type Error struct {
+	Msg   string
+	Token string
+	Pos   int
+}
+
+// Error returns a representation of the syntax error.
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return e.Msg + " at position " + strconv.Itoa(e.Pos)
+	}
+	return e.Msg + " near " + strconv.Quote(e.Token) + " at position " + strconv.Itoa(e.Pos)
+}
+
+// Error messages.
+const (
+	msgIllegal       = "illegal token"
+	msgUnexpected    = "unexpected token"
+	msgUnterminated  = "unterminated string"
+	msgComment       = "unterminated comment"
+	msgMissingField  = "missing field"
+	msgMissingReport = "missing report"
+	msgMissingValue  = "missing value"
+	msgBadOperator   = "invalid operator"
+	msgBadDateRange  = "invalid date range"
+	msgBadLimit      = "invalid limit"
+	msgBadFunction   = "unknown function"
+	msgAggregate     = "unexpected aggregate function"
+)
+
+// Aggregate functions.
+var functions = map[string]bool{
+	"AVG":   true,
+	"COUNT": true,
+	"MAX":   true,
+	"MIN":   true,
+	"SUM":   true,
+}
+
+// Operators of the conditions, other than the comparison ones.
+var operators = map[string]bool{
+	"IN":                           true,
+	"NOT_IN":                       true,
+	"STARTS_WITH":                  true,
+	"STARTS_WITH_IGNORE_CASE":      true,
+	"CONTAINS":                     true,
+	"CONTAINS_IGNORE_CASE":         true,
+	"DOES_NOT_CONTAIN":             true,
+	"DOES_NOT_CONTAIN_IGNORE_CASE": true,
+	"CONTAINS_ANY":                 true,
+	"CONTAINS_NONE":                true,
+	"CONTAINS_ALL":                 true,
+}
+
+// listOperators lists the operators expecting a list of values.
+var listOperators = map[string]bool{
+	"IN":            true,
+	"NOT_IN":        true,
+	"CONTAINS_ANY":  true,
+	"CONTAINS_NONE": true,
+	"CONTAINS_ALL":  true,
+}
+
+// DateRanges lists the predefined date ranges of the DURING clause.
+var DateRanges = []string{
+	"TODAY",
+	"YESTERDAY",
+	"LAST_7_DAYS",
+	"LAST_WEEK",
+	"LAST_BUSINESS_WEEK",
+	"THIS_MONTH",
+	"LAST_MONTH",
+	"ALL_TIME",
+	"LAST_14_DAYS",
+	"LAST_30_DAYS",
+	"THIS_WEEK_SUN_TODAY",
+	"THIS_WEEK_MON_TODAY",
+	"LAST_WEEK_SUN_SAT",
+}
+
+// DateLayout is the layout of the dates of a custom date range.
+const DateLayout = "20060102"
+
+// Parse parses the query and returns its statement.
+// Any syntax error is returned as *Error.
+func Parse(q string) (Statement, error) {
+	p := &parser{src: q, lx: NewLexer(q)}
+	p.next()
+
+	var (
+		s   Statement
+		err error
+	)
+	switch {
+	case p.tok.Keyword("SELECT"):
+		s, err = p.parseSelect()
+	case p.tok.Keyword("SHOW"):
+		s, err = p.parseShow()
+	case p.tok.Keyword("DESCRIBE"), p.tok.Keyword("DESC"):
+		s, err = p.parseDescribe()
+	default:
+		return nil, p.unexpected()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.Kind == SEMICOLON {
+		p.next()
+	}
+	if p.tok.Kind != EOF {
+		return nil, p.unexpected()
+	}
+	return s, nil
+}
+
+// NewError returns a syntax error with the given message on the token
+// found at this offset in the query.
+func NewError(q, msg, token string, offset int) *Error {
+	return &Error{Msg: msg, Token: token, Pos: utf8Pos(q, offset)}
+}
+
+// parser builds the statement from the tokens of the query.
+type parser struct {
+	src    string
+	lx     *Lexer
+	tok    Token
+	params []*Placeholder
+}
+
+// next moves to the next token.
+func (p *parser) next() {
+	p.tok = p.lx.Next()
+}
+
+// peek returns the token following the current one, without moving to it.
+func (p *parser) peek() Token {
+	lx := *p.lx
+	return lx.Next()
+}
+
+// error returns a syntax error on the given token.
+func (p *parser) error(msg string, tok Token) *Error {
+	return NewError(p.src, msg, tok.Lit, tok.Offset)
+}
+
+// unexpected returns a syntax error on the current token.
+func (p *parser) unexpected() *Error {
+	switch {
+	case p.tok.Kind == ILLEGAL && p.tok.Lit != "" && (p.tok.Lit[0] == '"' || p.tok.Lit[0] == '\''):
+		return p.error(msgUnterminated, p.tok)
+	case p.tok.Kind == ILLEGAL && strings.HasPrefix(p.tok.Lit, "/*"):
+		return p.error(msgComment, p.tok)
+	case p.tok.Kind == ILLEGAL:
+		return p.error(msgIllegal, p.tok)
+	}
+	return p.error(msgUnexpected, p.tok)
+}
+
+// ident returns the current token as identifier and moves to the next token.
+// At the end of the query, it returns a syntax error with the given message.
+func (p *parser) ident(msg string) (*Ident, error) {
+	if p.tok.Kind != IDENT {
+		if p.tok.Kind == EOF {
+			return nil, p.error(msg, p.tok)
+		}
+		return nil, p.unexpected()
+	}
+	id := &Ident{Name: p.tok.Lit, Offset: p.tok.Offset}
+	p.next()
+	return id, nil
+}
+
+// keyword returns a syntax error if the current token is not the given keyword.
+// Otherwise, it moves to the next token.
+func (p *parser) keyword(kw string) error {
+	if !p.tok.Keyword(kw) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+// parseSelect parses a SELECT statement, the current token being SELECT.
+func (p *parser) parseSelect() (*SelectStmt, error) {
+	s := &SelectStmt{}
+	p.next()
+
+	// Optimizer hints.
+	for p.tok.Kind == HINT {
+		hs, err := p.parseHints()
+		if err != nil {
+			return nil, err
+		}
+		s.Hints = append(s.Hints, hs...)
+		p.next()
+	}
+	// Selected columns.
+	for {
+		c, err := p.parseColumn(true)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Keyword("AS") {
+			p.next()
+			if c.Alias, err = p.ident(msgMissingField); err != nil {
+				return nil, err
+			}
+		}
+		s.Fields = append(s.Fields, c)
+		if p.tok.Kind != COMMA {
+			break
+		}
+		p.next()
+	}
+	// Report.
+	if err := p.keyword("FROM"); err != nil {
+		return nil, err
+	}
+	var err error
+	if s.From, err = p.ident(msgMissingReport); err != nil {
+		return nil, err
+	}
+	// Conditions.
+	if p.tok.Keyword("WHERE") {
+		p.next()
+		if s.Where, err = p.parseConditions(false); err != nil {
+			return nil, err
+		}
+	}
+	// Date range.
+	if p.tok.Keyword("DURING") {
+		if s.During, err = p.parseDuring(); err != nil {
+			return nil, err
+		}
+	}
+	// Groups.
+	if p.tok.Keyword("GROUP") {
+		p.next()
+		if err := p.keyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			f, err := p.ident(msgMissingField)
+			if err != nil {
+				return nil, err
+			}
+			s.GroupBy = append(s.GroupBy, f)
+			if p.tok.Kind != COMMA {
+				break
+			}
+			p.next()
+		}
+	}
+	// Conditions on groups.
+	if p.tok.Keyword("HAVING") {
+		p.next()
+		if s.Having, err = p.parseConditions(true); err != nil {
+			return nil, err
+		}
+	}
+	// Sort criteria.
+	if p.tok.Keyword("ORDER") {
+		p.next()
+		if err := p.keyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			f, err := p.parseColumn(true)
+			if err != nil {
+				return nil, err
+			}
+			o := &Order{Field: f}
+			if p.tok.Keyword("DESC") {
+				o.Desc = true
+				p.next()
+			} else if p.tok.Keyword("ASC") {
+				p.next()
+			}
+			s.OrderBy = append(s.OrderBy, o)
+			if p.tok.Kind != COMMA {
+				break
+			}
+			p.next()
+		}
+	}
+	// Limit.
+	if p.tok.Keyword("LIMIT") {
+		if s.Limit, err = p.parseLimit(); err != nil {
+			return nil, err
+		}
+	}
+	s.params = p.params
+
+	return s, nil
+}
+
+// parseShow parses the statements SHOW TABLES and SHOW [FULL] COLUMNS, the current token being SHOW.
+func (p *parser) parseShow() (Statement, error) {
+	p.next()
+	if p.tok.Keyword("TABLES") {
+		p.next()
+		s := &ShowTablesStmt{}
+		var err error
+		if s.Like, err = p.parseLike(); err != nil {
+			return nil, err
+		}
+		s.params = p.params
+		return s, nil
+	}
+	s := &DescribeStmt{}
+	if p.tok.Keyword("FULL") {
+		s.Full = true
+		p.next()
+	}
+	if !p.tok.Keyword("COLUMNS") && !p.tok.Keyword("FIELDS") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	if !p.tok.Keyword("FROM") && !p.tok.Keyword("IN") {
+		return nil, p.unexpected()
+	}
+	p.next()
+
+	var err error
+	if s.Table, err = p.ident(msgMissingReport); err != nil {
+		return nil, err
+	}
+	if s.Like, err = p.parseLike(); err != nil {
+		return nil, err
+	}
+	s.params = p.params
+
+	return s, nil
+}
+
+// parseDescribe parses the statement DESCRIBE, the current token being DESCRIBE or DESC.
+func (p *parser) parseDescribe() (Statement, error) {
+	p.next()
+
+	s := &DescribeStmt{}
+	var err error
+	if s.Table, err = p.ident(msgMissingReport); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseLike parses the optional LIKE clause and returns its pattern, a string or a placeholder.
+func (p *parser) parseLike() (Value, error) {
+	if !p.tok.Keyword("LIKE") {
+		return nil, nil
+	}
+	p.next()
+	switch p.tok.Kind {
+	case STRING, PLACEHOLDER, EOF:
+		return p.parseValue()
+	}
+	return nil, p.unexpected()
+}
+
+// parseHints parses the names of the hints of the current hint comment, separated by spaces or commas.
+func (p *parser) parseHints() ([]*Ident, error) {
+	var (
+		hs    []*Ident
+		start = p.tok.Offset + len("/*+")
+		lx    = NewLexer(p.tok.Lit[len("/*+") : len(p.tok.Lit)-len("*/")])
+	)
+	for tok := lx.Next(); tok.Kind != EOF; tok = lx.Next() {
+		tok.Offset += start
+		switch tok.Kind {
+		case IDENT:
+			hs = append(hs, &Ident{Name: tok.Lit, Offset: tok.Offset})
+		case COMMA:
+		case ILLEGAL:
+			return nil, p.error(msgIllegal, tok)
+		default:
+			return nil, p.error(msgUnexpected, tok)
+		}
+	}
+	return hs, nil
+}
+
+// parseColumn parses a field or, if allowed, an aggregate function of a field.
+func (p *parser) parseColumn(aggregate bool) (*Column, error) {
+	if p.tok.Kind != IDENT || p.peek().Kind != LPAREN {
+		f, err := p.ident(msgMissingField)
+		if err != nil {
+			return nil, err
+		}
+		return &Column{Field: f, Offset: f.Offset}, nil
+	}
+	fn := strings.ToUpper(p.tok.Lit)
+	if !functions[fn] {
+		return nil, p.error(msgBadFunction, p.tok)
+	}
+	if !aggregate {
+		return nil, p.error(msgAggregate, p.tok)
+	}
+	c := &Column{Func: fn, Offset: p.tok.Offset}
+	p.next()
+	p.next()
+
+	if fn == "COUNT" && p.tok.Kind == STAR {
+		c.Field = &Ident{Name: p.tok.Lit, Offset: p.tok.Offset}
+		p.next()
+	} else {
+		var err error
+		if c.Field, err = p.ident(msgMissingField); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.Kind != RPAREN {
+		return nil, p.unexpected()
+	}
+	p.next()
+	return c, nil
+}
+
+// parseConditions parses the predicates of the WHERE or HAVING clause.
+func (p *parser) parseConditions(having bool) ([]*Condition, error) {
+	var cs []*Condition
+	for {
+		c, err := p.parseCondition(having)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+		if !p.tok.Keyword("AND") {
+			return cs, nil
+		}
+		p.next()
+	}
+}
+
+// parseCondition parses a predicate of the WHERE clause or, with having, of the HAVING clause.
+// Only the predicates of the HAVING clause can use aggregate functions,
+// and their operator must be a comparison one.
+func (p *parser) parseCondition(having bool) (*Condition, error) {
+	f, err := p.parseColumn(having)
+	if err != nil {
+		return nil, err
+	}
+	c := &Condition{Field: f}
+	switch p.tok.Kind {
+	case EQ, NEQ, LT, LTE, GT, GTE:
+		c.Operator = p.tok.Lit
+	case IDENT:
+		if having {
+			return nil, p.error(msgBadOperator, p.tok)
+		}
+		c.Operator = strings.ToUpper(p.tok.Lit)
+		if !operators[c.Operator] {
+			return nil, p.error(msgBadOperator, p.tok)
+		}
+	case ILLEGAL:
+		return nil, p.unexpected()
+	default:
+		return nil, p.error(msgBadOperator, p.tok)
+	}
+	p.next()
+
+	if listOperators[c.Operator] && p.tok.Kind == LBRACKET {
+		c.Value, err = p.parseList()
+	} else {
+		c.Value, err = p.parseValue()
+	}
+	return c, err
+}
+
+// parseList parses a list of values enclosed by square brackets.
+func (p *parser) parseList() (*List, error) {
+	l := &List{Offset: p.tok.Offset}
+	p.next()
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		l.Values = append(l.Values, v)
+		if p.tok.Kind != COMMA {
+			break
+		}
+		p.next()
+	}
+	if p.tok.Kind != RBRACKET {
+		return nil, p.unexpected()
+	}
+	p.next()
+	return l, nil
+}
+
+// parseValue parses a literal or a placeholder.
+func (p *parser) parseValue() (Value, error) {
+	var v Value
+	switch p.tok.Kind {
+	case NUMBER, STRING, IDENT:
+		v = &Literal{Kind: p.tok.Kind, Raw: p.tok.Lit, Offset: p.tok.Offset}
+	case PLACEHOLDER:
+		v = p.placeholder()
+	case EOF:
+		return nil, p.error(msgMissingValue, p.tok)
+	default:
+		return nil, p.unexpected()
+	}
+	p.next()
+	return v, nil
+}
+
+// placeholder registers the current token as a new parameter.
+func (p *parser) placeholder() *Placeholder {
+	ph := &Placeholder{Index: len(p.params), Offset: p.tok.Offset}
+	if p.tok.Lit != "?" {
+		ph.Name = p.tok.Lit
+	}
+	p.params = append(p.params, ph)
+	return ph
+}
+
+// parseDuring parses the DURING clause, the current token being DURING.
+// The date range is a predefined one, as LAST_7_DAYS, or a custom one with
+// its start and end dates, as 20170101,20170131.
+func (p *parser) parseDuring() (*During, error) {
+	d := &During{Offset: p.tok.Offset}
+	p.next()
+
+	switch p.tok.Kind {
+	case IDENT:
+		if !isDateRange(p.tok.Lit) {
+			return nil, p.error(msgBadDateRange, p.tok)
+		}
+		d.Range = &Ident{Name: strings.ToUpper(p.tok.Lit), Offset: p.tok.Offset}
+		p.next()
+		return d, nil
+	case PLACEHOLDER:
+		// A date range or the start date.
+		d.Start = p.placeholder()
+		p.next()
+		if p.tok.Kind != COMMA {
+			return d, nil
+		}
+	case NUMBER:
+		if !isDate(p.tok.Lit) {
+			return nil, p.error(msgBadDateRange, p.tok)
+		}
+		d.Start = &Literal{Kind: NUMBER, Raw: p.tok.Lit, Offset: p.tok.Offset}
+		p.next()
+		if p.tok.Kind != COMMA {
+			return nil, p.error(msgBadDateRange, p.tok)
+		}
+	case EOF:
+		return nil, p.error(msgBadDateRange, p.tok)
+	default:
+		return nil, p.unexpected()
+	}
+	// End date.
+	p.next()
+	switch p.tok.Kind {
+	case PLACEHOLDER:
+		d.End = p.placeholder()
+	case NUMBER:
+		if !isDate(p.tok.Lit) {
+			return nil, p.error(msgBadDateRange, p.tok)
+		}
+		d.End = &Literal{Kind: NUMBER, Raw: p.tok.Lit, Offset: p.tok.Offset}
+	default:
+		return nil, p.error(msgBadDateRange, p.tok)
+	}
+	p.next()
+	return d, nil
+}
+
+// parseLimit parses the LIMIT clause, the current token being LIMIT.
+func (p *parser) parseLimit() (*Limit, error) {
+	l := &Limit{Offset: p.tok.Offset}
+	p.next()
+
+	var err error
+	if l.Count, err = p.integer(); err != nil {
+		return nil, err
+	}
+	if p.tok.Kind == COMMA {
+		p.next()
+		l.Start = l.Count
+		if l.Count, err = p.integer(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// integer parses a positive integer.
+func (p *parser) integer() (int, error) {
+	if p.tok.Kind != NUMBER {
+		return 0, p.error(msgBadLimit, p.tok)
+	}
+	i, err := strconv.Atoi(p.tok.Lit)
+	if err != nil || i < 0 {
+		return 0, p.error(msgBadLimit, p.tok)
+	}
+	p.next()
+	return i, nil
+}
+
+// isDate returns true if the literal is a date formatted as YYYYMMDD.
+func isDate(lit string) bool {
+	if len(lit) != len(DateLayout) {
+		return false
+	}
+	_, err := time.Parse(DateLayout, lit)
+	return err == nil
+}
+
+// isDateRange returns true if the literal is a predefined date range.
+func isDateRange(lit string) bool {
+	for _, r := range DateRanges {
+		if strings.EqualFold(lit, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// utf8Pos returns the position in characters, starting at 1, of the byte offset.
+func utf8Pos(s string, offset int) int {
+	if offset > len(s) {
+		offset = len(s)
+	}
+	return len([]rune(s[:offset])) + 1
+}
@@ -0,0 +1,159 @@
+package parser_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// TestParse tests the function named Parse.
+func TestParse(t *testing.T) {
+	var parseTests = []struct {
+		in, out string
+		params  int
+	}{
+		{
+			in:  "SELECT CampaignId FROM CAMPAIGN_PERFORMANCE_REPORT",
+			out: "SELECT CampaignId FROM CAMPAIGN_PERFORMANCE_REPORT",
+		},
+		{
+			in:  "select CampaignId, Cost from CAMPAIGN_PERFORMANCE_REPORT;",
+			out: "SELECT CampaignId, Cost FROM CAMPAIGN_PERFORMANCE_REPORT",
+		},
+		{
+			in:     "SELECT Id FROM R WHERE Name = 'a?b' AND Cost >= ? AND Status IN [ENABLED, ?] AND Name STARTS_WITH_IGNORE_CASE \"rv\"",
+			out:    "SELECT Id FROM R WHERE Name = 'a?b' AND Cost >= ? AND Status IN [ENABLED, ?] AND Name STARTS_WITH_IGNORE_CASE \"rv\"",
+			params: 2,
+		},
+		{
+			in:     "SELECT Id FROM R WHERE Status not_in ? during last_7_days",
+			out:    "SELECT Id FROM R WHERE Status NOT_IN ? DURING LAST_7_DAYS",
+			params: 1,
+		},
+		{
+			in:  "SELECT Id FROM R DURING 20170101,20170131 ORDER BY Id DESC, Cost LIMIT 5",
+			out: "SELECT Id FROM R DURING 20170101,20170131 ORDER BY Id DESC, Cost ASC LIMIT 5",
+		},
+		{
+			in:     "SELECT Id FROM R DURING ?, ? LIMIT 10, 5",
+			out:    "SELECT Id FROM R DURING ?,? LIMIT 10, 5",
+			params: 2,
+		},
+		{
+			in:     "SELECT Id FROM R WHERE Id = :id AND Status IN @status AND Name != ? DURING :start, :end",
+			out:    "SELECT Id FROM R WHERE Id = :id AND Status IN @status AND Name != ? DURING :start,:end",
+			params: 5,
+		},
+		{
+			in:     "SELECT Id FROM R DURING ?",
+			out:    "SELECT Id FROM R DURING ?",
+			params: 1,
+		},
+		{
+			in:  "SELECT CampaignName AS name, sum(Cost) as cost, Count(*) FROM R GROUP BY name HAVING SUM(Cost) > 10 AND COUNT(*) != 1 ORDER BY cost DESC",
+			out: "SELECT CampaignName AS name, SUM(Cost) AS cost, COUNT(*) FROM R GROUP BY name HAVING SUM(Cost) > 10 AND COUNT(*) != 1 ORDER BY cost DESC",
+		},
+		{
+			in:     "SELECT Id, MIN(Cost), MAX(Cost), AVG(Clicks) FROM R WHERE Clicks > ? GROUP BY Id HAVING MIN(Cost) >= ? ORDER BY MAX(Cost)",
+			out:    "SELECT Id, MIN(Cost), MAX(Cost), AVG(Clicks) FROM R WHERE Clicks > ? GROUP BY Id HAVING MIN(Cost) >= ? ORDER BY MAX(Cost) ASC",
+			params: 2,
+		},
+		{in: "show tables", out: "SHOW TABLES"},
+		{in: "SHOW TABLES LIKE 'CAMPAIGN%';", out: "SHOW TABLES LIKE 'CAMPAIGN%'"},
+		{in: "show full columns from R like ?", out: "SHOW FULL COLUMNS FROM R LIKE ?", params: 1},
+		{in: "SHOW FIELDS IN R", out: "SHOW COLUMNS FROM R"},
+		{in: "DESCRIBE R", out: "SHOW COLUMNS FROM R"},
+		{in: "desc R;", out: "SHOW COLUMNS FROM R"},
+	}
+	for i, pt := range parseTests {
+		s, err := parser.Parse(pt.in)
+		if err != nil {
+			t.Fatalf("%d. Expected no error with %s, received %v", i, pt.in, err)
+		}
+		if out := s.String(); out != pt.out {
+			t.Errorf("%d. Expected %s, received %s", i, pt.out, out)
+		}
+		if n := len(s.Params()); n != pt.params {
+			t.Errorf("%d. Expected %d params, received %d", i, pt.params, n)
+		}
+	}
+}
+
+// TestParse_Hints tests the hints of a statement.
+func TestParse_Hints(t *testing.T) {
+	s, err := parser.Parse("SELECT /*+ zero_impressions, raw_enums */ /* comment */ /*+ no_raw_enums */ Id FROM R /* end */")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	exp := []*parser.Ident{
+		{Name: "zero_impressions", Offset: 11},
+		{Name: "raw_enums", Offset: 29},
+		{Name: "no_raw_enums", Offset: 60},
+	}
+	if hs := s.(*parser.SelectStmt).Hints; !reflect.DeepEqual(hs, exp) {
+		t.Errorf("Expected %v as hints, received %v", exp, hs)
+	}
+	if out := s.String(); out != "SELECT Id FROM R" {
+		t.Errorf("Expected the query without its comments, received %s", out)
+	}
+}
+
+// TestParse_Error tests the syntax errors returned by the function named Parse.
+func TestParse_Error(t *testing.T) {
+	var errorTests = []struct {
+		in  string
+		err *parser.Error
+	}{
+		{in: "", err: &parser.Error{Msg: "unexpected token", Pos: 1}},
+		{in: "UPDATE R", err: &parser.Error{Msg: "unexpected token", Token: "UPDATE", Pos: 1}},
+		{in: "SELECT", err: &parser.Error{Msg: "missing field", Pos: 7}},
+		{in: "SELECT Id FORM R", err: &parser.Error{Msg: "unexpected token", Token: "FORM", Pos: 11}},
+		{in: "SELECT Id FROM", err: &parser.Error{Msg: "missing report", Pos: 15}},
+		{in: "SELECT Id FROM R WHERE Name ~ 1", err: &parser.Error{Msg: "illegal token", Token: "~", Pos: 29}},
+		{in: "SELECT Id FROM R WHERE Name LIKE 1", err: &parser.Error{Msg: "invalid operator", Token: "LIKE", Pos: 29}},
+		{in: "SELECT Id FROM R WHERE Name = ", err: &parser.Error{Msg: "missing value", Pos: 31}},
+		{in: "SELECT Id FROM R WHERE Name = 'rv", err: &parser.Error{Msg: "unterminated string", Token: "'rv", Pos: 31}},
+		{in: "SELECT Id FROM R WHERE Id IN [1, 2", err: &parser.Error{Msg: "unexpected token", Pos: 35}},
+		{in: "SELECT Id FROM R DURING LAST_YEAR", err: &parser.Error{Msg: "invalid date range", Token: "LAST_YEAR", Pos: 25}},
+		{in: "SELECT Id FROM R DURING 20171301,20171231", err: &parser.Error{Msg: "invalid date range", Token: "20171301", Pos: 25}},
+		{in: "SELECT Id FROM R DURING 20170101", err: &parser.Error{Msg: "invalid date range", Pos: 33}},
+		{in: "SELECT Id FROM R ORDER Id", err: &parser.Error{Msg: "unexpected token", Token: "Id", Pos: 24}},
+		{in: "SELECT Id FROM R LIMIT -1", err: &parser.Error{Msg: "invalid limit", Token: "-1", Pos: 24}},
+		{in: "SELECT Id FROM R LIMIT 5 OOPS", err: &parser.Error{Msg: "unexpected token", Token: "OOPS", Pos: 26}},
+		{in: "SELECT MEDIAN(Cost) FROM R", err: &parser.Error{Msg: "unknown function", Token: "MEDIAN", Pos: 8}},
+		{in: "SELECT SUM(*) FROM R", err: &parser.Error{Msg: "unexpected token", Token: "*", Pos: 12}},
+		{in: "SELECT Id AS FROM R", err: &parser.Error{Msg: "unexpected token", Token: "R", Pos: 19}},
+		{in: "SELECT Id FROM R WHERE SUM(Cost) > 1", err: &parser.Error{Msg: "unexpected aggregate function", Token: "SUM", Pos: 24}},
+		{in: "SELECT Id FROM R GROUP BY", err: &parser.Error{Msg: "missing field", Pos: 26}},
+		{in: "SELECT Id FROM R GROUP BY Id HAVING Id IN [1]", err: &parser.Error{Msg: "invalid operator", Token: "IN", Pos: 40}},
+		{in: "SELECT Id FROM R /* oops", err: &parser.Error{Msg: "unterminated comment", Token: "/* oops", Pos: 18}},
+		{in: "SELECT /*+ raw-enums */ Id FROM R", err: &parser.Error{Msg: "illegal token", Token: "-", Pos: 15}},
+		{in: "SELECT Id /*+ raw_enums */ FROM R", err: &parser.Error{Msg: "unexpected token", Token: "/*+ raw_enums */", Pos: 11}},
+		{in: "SHOW DATABASES", err: &parser.Error{Msg: "unexpected token", Token: "DATABASES", Pos: 6}},
+		{in: "SHOW COLUMNS R", err: &parser.Error{Msg: "unexpected token", Token: "R", Pos: 14}},
+		{in: "SHOW TABLES LIKE 1", err: &parser.Error{Msg: "unexpected token", Token: "1", Pos: 18}},
+		{in: "SHOW TABLES LIKE", err: &parser.Error{Msg: "missing value", Pos: 17}},
+		{in: "DESCRIBE", err: &parser.Error{Msg: "missing report", Pos: 9}},
+		{in: "DESCRIBE R Id", err: &parser.Error{Msg: "unexpected token", Token: "Id", Pos: 12}},
+		{in: "SELECT Nom FROM R WHERE Nom = 'é' ?", err: &parser.Error{Msg: "unexpected token", Token: "?", Pos: 35}},
+	}
+	for i, et := range errorTests {
+		_, err := parser.Parse(et.in)
+		if !reflect.DeepEqual(err, et.err) {
+			t.Errorf("%d. Expected %v with %s, received %v", i, et.err, et.in, err)
+		}
+	}
+}
+
+// TestError_Error tests the method Error on the Error struct.
+func TestError_Error(t *testing.T) {
+	err := &parser.Error{Msg: "unexpected token", Token: "FORM", Pos: 11}
+	if s := err.Error(); s != `unexpected token near "FORM" at position 11` {
+		t.Errorf("Unexpected error message: %s", s)
+	}
+	err = &parser.Error{Msg: "missing report", Pos: 15}
+	if s := err.Error(); s != "missing report at position 15" {
+		t.Errorf("Unexpected error message: %s", s)
+	}
+}
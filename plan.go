@@ -0,0 +1,675 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// plan represents the execution of a statement: the query sent to the Adwords API
+// with the options of its downloads, and the local processing of its report for the clauses not supported by the API.
+//
+// The fields to fetch start with the selected ones, followed by the ones only
+// required by the local processing. Without aggregation, the processed rows are
+// the fetched ones. Otherwise, the rows are grouped and each processed row contains
+// the outputs, starting with the selected columns. Only the first columns are returned.
+type plan struct {
+	query   string
+	opts    *Opts
+	fields  []string
+	columns int
+	names   []string
+	outs    []*output
+	groupBy []int
+	having  []*filter
+	orderBy []sortKey
+	limit   *parser.Limit
+}
+
+// output represents a column computed from the fetched fields of a group.
+// For COUNT(*), the index of the fetched field is -1.
+type output struct {
+	col   *parser.Column
+	index int
+}
+
+// filter represents a predicate of the HAVING clause on the output at this index.
+type filter struct {
+	index int
+	op    string
+	value driver.Value
+}
+
+// sortKey represents a sort criterion on the column at this index.
+type sortKey struct {
+	index int
+	desc  bool
+}
+
+// Error messages of the local processing.
+const (
+	msgNotGrouped = "field not grouped"
+	msgBadValue   = "invalid value"
+)
+
+// newPlan returns the execution plan of the statement of the query q.
+// The aliases, and the GROUP BY, HAVING, ORDER BY and LIMIT clauses are removed
+// from the query sent to the API in order to be applied locally.
+func newPlan(st *parser.SelectStmt, q string) (*plan, error) {
+	p := &plan{query: q, columns: len(st.Fields), limit: st.Limit}
+
+	var err error
+	if st.Aggregated() {
+		err = p.aggregate(st, q)
+	} else {
+		err = p.plain(st)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.local(st) {
+		api := *st
+		api.Fields = make([]*parser.Column, len(p.fields))
+		for k, f := range p.fields {
+			api.Fields[k] = &parser.Column{Field: &parser.Ident{Name: f}}
+		}
+		api.GroupBy, api.Having, api.OrderBy, api.Limit = nil, nil, nil, nil
+		p.query = api.String()
+	}
+	return p, nil
+}
+
+// local returns true if the statement requires a local processing.
+func (p *plan) local(st *parser.SelectStmt) bool {
+	if p.outs != nil || len(st.OrderBy) > 0 || st.Limit != nil {
+		return true
+	}
+	for _, c := range st.Fields {
+		if c.Alias != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// plain prepares a statement without aggregation.
+func (p *plan) plain(st *parser.SelectStmt) error {
+	p.fields = st.FieldNames()
+	p.names = make([]string, p.columns)
+	for k, c := range st.Fields {
+		if c.Alias != nil {
+			p.names[k] = c.Alias.Name
+		}
+	}
+	for _, o := range st.OrderBy {
+		k := aliasIndex(st.Fields, o.Field)
+		if k < 0 {
+			k = p.fetch(o.Field.Field.Name)
+		}
+		p.orderBy = append(p.orderBy, sortKey{index: k, desc: o.Desc})
+	}
+	return nil
+}
+
+// aggregate prepares a statement with aggregation.
+func (p *plan) aggregate(st *parser.SelectStmt, q string) error {
+	for _, c := range st.Fields {
+		p.output(c)
+	}
+	for _, g := range st.GroupBy {
+		name := g.Name
+		if k := aliasIndex(st.Fields, &parser.Column{Field: g}); k >= 0 {
+			// Group by alias.
+			name = st.Fields[k].Field.Name
+		}
+		p.groupBy = append(p.groupBy, p.fetch(name))
+	}
+	for _, c := range st.Fields {
+		if c.Func == "" && !p.grouped(c.Field.Name) {
+			return newSyntaxError(parser.NewError(q, msgNotGrouped, c.Field.Name, c.Offset))
+		}
+	}
+	for _, c := range st.Having {
+		k, err := p.resolve(st, c.Field, q)
+		if err != nil {
+			return err
+		}
+		l, ok := c.Value.(*parser.Literal)
+		if !ok {
+			return newSyntaxError(parser.NewError(q, msgBadValue, c.Value.String(), c.Value.Pos()))
+		}
+		p.having = append(p.having, &filter{index: k, op: c.Operator, value: literalValue(l)})
+	}
+	for _, o := range st.OrderBy {
+		k, err := p.resolve(st, o.Field, q)
+		if err != nil {
+			return err
+		}
+		p.orderBy = append(p.orderBy, sortKey{index: k, desc: o.Desc})
+	}
+	return nil
+}
+
+// resolve returns the index of the output matching the column of a HAVING or ORDER BY clause.
+// The column is an alias, a selected column or a new output.
+func (p *plan) resolve(st *parser.SelectStmt, c *parser.Column, q string) (int, error) {
+	if k := aliasIndex(st.Fields, c); k >= 0 {
+		return k, nil
+	}
+	for k, o := range p.outs {
+		if o.col.Func == c.Func && strings.EqualFold(o.col.Field.Name, c.Field.Name) {
+			return k, nil
+		}
+	}
+	if c.Func == "" && !p.grouped(c.Field.Name) {
+		return 0, newSyntaxError(parser.NewError(q, msgNotGrouped, c.Field.Name, c.Offset))
+	}
+	return p.output(c), nil
+}
+
+// output adds the column as output and returns its index.
+func (p *plan) output(c *parser.Column) int {
+	o := &output{col: c, index: -1}
+	if c.Func != "COUNT" || c.Field.Name != "*" {
+		o.index = p.fetch(c.Field.Name)
+	}
+	p.outs = append(p.outs, o)
+	return len(p.outs) - 1
+}
+
+// fetch returns the index of the field to fetch, adding it if necessary.
+func (p *plan) fetch(name string) int {
+	if k := fieldIndex(p.fields, name); k >= 0 {
+		return k
+	}
+	p.fields = append(p.fields, name)
+	return len(p.fields) - 1
+}
+
+// grouped returns true if the field is used to group the rows.
+func (p *plan) grouped(name string) bool {
+	for _, k := range p.groupBy {
+		if strings.EqualFold(p.fields[k], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// run applies the local processing on the rows of the report.
+func (p *plan) run(rs *Rows) (*Rows, error) {
+	width := len(p.fields)
+	if p.outs != nil {
+		data, err := readAll(rs.src, width)
+		if err != nil {
+			return nil, err
+		}
+		rs.src = &valuesSource{data: p.group(data)}
+		if rs.report != nil {
+			// The summary of the report does not match the aggregated rows.
+			rs.report.summary = nil
+		}
+		rs.cols, rs.fields = p.outputs(rs.cols, rs.fields)
+		width = len(p.outs)
+	} else {
+		// The columns of the rows may be shared, the aliases are set on a copy.
+		cols := make([]string, len(rs.cols))
+		copy(cols, rs.cols)
+		for k, n := range p.names {
+			if n != "" && k < len(cols) {
+				cols[k] = n
+			}
+		}
+		rs.cols = cols
+	}
+	if len(p.orderBy) > 0 {
+		data, err := readAll(rs.src, width)
+		if err != nil {
+			return nil, err
+		}
+		p.sort(data)
+		rs.src = &valuesSource{data: data}
+	}
+	if p.limit != nil {
+		rs.src = &limitSource{source: rs.src, start: p.limit.Start, count: p.limit.Count}
+	}
+	if width > p.columns {
+		// Hides the columns only used locally.
+		rs.src = &projectSource{source: rs.src, buf: make([]driver.Value, width)}
+		if len(rs.cols) > p.columns {
+			rs.cols = rs.cols[:p.columns]
+		}
+		if len(rs.fields) > p.columns {
+			rs.fields = rs.fields[:p.columns]
+		}
+	}
+	return rs, nil
+}
+
+// outputs returns the names and the fields of the outputs,
+// based on those of the fetched fields.
+func (p *plan) outputs(cols []string, fields []*field) ([]string, []*field) {
+	names := make([]string, len(p.outs))
+	fs := make([]*field, len(p.outs))
+	for k, o := range p.outs {
+		f := fieldAt(fields, o.index)
+		switch {
+		case o.col.Alias != nil:
+			names[k] = o.col.Alias.Name
+		case o.col.Func == "" && o.index < len(cols):
+			names[k] = cols[o.index]
+		default:
+			names[k] = o.col.Expr()
+		}
+		fs[k] = aggregateField(o.col, f)
+	}
+	return names, fs
+}
+
+// group groups the fetched rows and returns the outputs of each group
+// matching the HAVING clause, in order of appearance of the groups.
+// Without GROUP BY clause, all the rows belong to the same group.
+func (p *plan) group(data [][]driver.Value) [][]driver.Value {
+	type group struct {
+		row  []driver.Value
+		accs []*accumulator
+	}
+	var (
+		groups []*group
+		keys   = make(map[string]*group)
+	)
+	if len(p.groupBy) == 0 {
+		keys[""] = &group{}
+		groups = append(groups, keys[""])
+	}
+	for _, row := range data {
+		key := groupKey(row, p.groupBy)
+		g, ok := keys[key]
+		if !ok {
+			g = &group{}
+			keys[key] = g
+			groups = append(groups, g)
+		}
+		if g.row == nil {
+			g.row = row
+			g.accs = make([]*accumulator, len(p.outs))
+			for k, o := range p.outs {
+				if o.col.Func != "" {
+					g.accs[k] = &accumulator{fn: o.col.Func}
+				}
+			}
+		}
+		for k, o := range p.outs {
+			if g.accs[k] != nil {
+				if o.index < 0 {
+					g.accs[k].add(true)
+				} else {
+					g.accs[k].add(row[o.index])
+				}
+			}
+		}
+	}
+	out := make([][]driver.Value, 0, len(groups))
+	for _, g := range groups {
+		row := make([]driver.Value, len(p.outs))
+		for k, o := range p.outs {
+			switch {
+			case g.accs != nil && g.accs[k] != nil:
+				row[k] = g.accs[k].value()
+			case g.row != nil && o.index >= 0:
+				row[k] = g.row[o.index]
+			case o.col.Func == "COUNT":
+				// Empty report.
+				row[k] = int64(0)
+			}
+		}
+		if p.match(row) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// match returns true if the outputs match the predicates of the HAVING clause.
+func (p *plan) match(row []driver.Value) bool {
+	for _, f := range p.having {
+		v := row[f.index]
+		if v == nil {
+			return false
+		}
+		c := compare(v, f.value)
+		switch f.op {
+		case "=":
+			if c != 0 {
+				return false
+			}
+		case "!=":
+			if c == 0 {
+				return false
+			}
+		case "<":
+			if c >= 0 {
+				return false
+			}
+		case "<=":
+			if c > 0 {
+				return false
+			}
+		case ">":
+			if c <= 0 {
+				return false
+			}
+		case ">=":
+			if c < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sort sorts the rows with the sort criteria.
+func (p *plan) sort(data [][]driver.Value) {
+	sort.SliceStable(data, func(i, j int) bool {
+		for _, o := range p.orderBy {
+			c := compare(data[i][o.index], data[j][o.index])
+			if c == 0 {
+				continue
+			}
+			if o.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// aliasIndex returns the index of the selected column with the alias named as the column.
+// It returns -1 if the column is not an alias.
+func aliasIndex(cols []*parser.Column, c *parser.Column) int {
+	if c.Func != "" {
+		return -1
+	}
+	for k, s := range cols {
+		if s.Alias != nil && strings.EqualFold(s.Alias.Name, c.Field.Name) {
+			return k
+		}
+	}
+	return -1
+}
+
+// groupKey returns the key of the group of the row.
+func groupKey(row []driver.Value, groupBy []int) string {
+	var b strings.Builder
+	for _, k := range groupBy {
+		fmt.Fprintf(&b, "%T:%v\x00", row[k], row[k])
+	}
+	return b.String()
+}
+
+// literalValue returns the value of the literal.
+// The numbers are converted to int64 or float64.
+func literalValue(l *parser.Literal) driver.Value {
+	if l.Kind == parser.NUMBER {
+		if i, err := strconv.ParseInt(l.Raw, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(l.Raw, 64); err == nil {
+			return f
+		}
+	}
+	return l.Text()
+}
+
+// aggregateField returns the field describing the values of the column,
+// based on the field f of its values. f is nil if unknown.
+func aggregateField(c *parser.Column, f *field) *field {
+	a := &field{Name: c.Expr(), DisplayName: c.Name(), Behavior: behaviorMetric}
+	switch c.Func {
+	case "":
+		return f
+	case "COUNT":
+		a.Type = typeLong
+	case "AVG":
+		a.Type = typeDouble
+	case "SUM":
+		if f == nil {
+			a.Type = typeDouble
+			break
+		}
+		switch f.Type {
+		case typeInteger, typeLong, typeMoney:
+			a.Type = f.Type
+		default:
+			a.Type = typeDouble
+		}
+	default:
+		if f == nil {
+			return nil
+		}
+		a.Type = f.Type
+		a.Behavior = f.Behavior
+	}
+	return a
+}
+
+// accumulator computes an aggregate function over values.
+// As in SQL, the nil values are ignored.
+type accumulator struct {
+	fn    string
+	n     int64
+	isum  int64
+	fsum  float64
+	float bool
+	val   driver.Value
+}
+
+// add adds the value to the aggregate.
+func (a *accumulator) add(v driver.Value) {
+	if v == nil {
+		return
+	}
+	switch a.fn {
+	case "COUNT":
+		a.n++
+	case "SUM", "AVG":
+		switch x := v.(type) {
+		case int64:
+			a.isum += x
+		case float64:
+			a.fsum += x
+			a.float = true
+		default:
+			// Not a number.
+			return
+		}
+		a.n++
+	case "MIN":
+		if a.val == nil || compare(v, a.val) < 0 {
+			a.val = v
+		}
+	case "MAX":
+		if a.val == nil || compare(v, a.val) > 0 {
+			a.val = v
+		}
+	}
+}
+
+// value returns the result of the aggregate function.
+func (a *accumulator) value() driver.Value {
+	switch a.fn {
+	case "COUNT":
+		return a.n
+	case "SUM":
+		if a.n == 0 {
+			return nil
+		}
+		if a.float {
+			return a.fsum + float64(a.isum)
+		}
+		return a.isum
+	case "AVG":
+		if a.n == 0 {
+			return nil
+		}
+		return (a.fsum + float64(a.isum)) / float64(a.n)
+	}
+	return a.val
+}
+
+// fieldIndex returns the index of the field in the list, -1 if not found.
+// The field names are compared without regard to their case.
+func fieldIndex(fields []string, name string) int {
+	for k, f := range fields {
+		if strings.EqualFold(f, name) {
+			return k
+		}
+	}
+	return -1
+}
+
+// compare returns an integer comparing two values: 0 if a == b, -1 if a < b, and +1 if a > b.
+// The nil value is lower than any other value.
+// Values of different types are compared as strings.
+func compare(a, b driver.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareFloat(float64(x), float64(y))
+		case float64:
+			return compareFloat(float64(x), y)
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return compareFloat(x, float64(y))
+		case float64:
+			return compareFloat(x, y)
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case y:
+				return -1
+			}
+			return 1
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// readAll reads all the rows of the source and closes it.
+func readAll(src source, width int) ([][]driver.Value, error) {
+	defer src.Close()
+
+	var data [][]driver.Value
+	for {
+		row := make([]driver.Value, width)
+		err := src.Next(row)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, row)
+	}
+}
+
+// valuesSource provides the rows kept in memory.
+type valuesSource struct {
+	data [][]driver.Value
+	pos  int
+}
+
+// Close implements the source interface.
+func (s *valuesSource) Close() error {
+	return nil
+}
+
+// Next copies the next row into the provided slice.
+func (s *valuesSource) Next(dest []driver.Value) error {
+	if s.pos >= len(s.data) {
+		return io.EOF
+	}
+	copy(dest, s.data[s.pos])
+	s.pos++
+	return nil
+}
+
+// limitSource skips the first rows of its source and stops after a maximum of rows.
+type limitSource struct {
+	source
+	start, count, n int
+}
+
+// Next provides the next row until the limit is reached.
+func (s *limitSource) Next(dest []driver.Value) error {
+	for ; s.start > 0; s.start-- {
+		if err := s.source.Next(dest); err != nil {
+			return err
+		}
+	}
+	if s.n >= s.count {
+		return io.EOF
+	}
+	s.n++
+	return s.source.Next(dest)
+}
+
+// projectSource only provides the first columns of its source.
+type projectSource struct {
+	source
+	buf []driver.Value
+}
+
+// Next copies the first columns of the next row into the provided slice.
+func (s *projectSource) Next(dest []driver.Value) error {
+	if err := s.source.Next(s.buf); err != nil {
+		return err
+	}
+	copy(dest, s.buf)
+	return nil
+}
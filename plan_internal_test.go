@@ -0,0 +1,202 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// runPlan executes the query on the CSV report and returns the columns and rows.
+func runPlan(t *testing.T, q, csv string) (*plan, []string, [][]driver.Value) {
+	st, err := parser.Parse(q)
+	if err != nil {
+		t.Fatalf("Expected no error with %s, received %v", q, err)
+	}
+	s := st.(*parser.SelectStmt)
+	p, err := newPlan(s, q)
+	if err != nil {
+		t.Fatalf("Expected no error with %s, received %v", q, err)
+	}
+	rs, err := newCSVRows(
+		ioutil.NopCloser(strings.NewReader(csv)), nil,
+		lookupFields(APIVersion, s.From.Name, p.fields),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error with %s, received %v", csv, err)
+	}
+	if rs, err = p.run(rs); err != nil {
+		t.Fatalf("Expected no error with %s, received %v", q, err)
+	}
+	defer rs.Close()
+
+	var data [][]driver.Value
+	for {
+		row := make([]driver.Value, len(rs.Columns()))
+		if err := rs.Next(row); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Expected no error with %s, received %v", q, err)
+		}
+		data = append(data, row)
+	}
+	return p, rs.Columns(), data
+}
+
+// TestPlan tests the local processing of the aliases and of the GROUP BY, HAVING, ORDER BY and LIMIT clauses.
+func TestPlan(t *testing.T) {
+	const report = "Campaign,Clicks,Cost\na,19,100\nb,21,--\nc,19,50\n"
+	var planTests = []struct {
+		in, out, csv string
+		cols         []string
+		data         [][]driver.Value
+	}{
+		{
+			in:   "SELECT CampaignName, Clicks, Cost FROM CAMPAIGN_PERFORMANCE_REPORT",
+			out:  "SELECT CampaignName, Clicks, Cost FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  report,
+			cols: []string{"Campaign", "Clicks", "Cost"},
+			data: [][]driver.Value{{"a", int64(19), int64(100)}, {"b", int64(21), nil}, {"c", int64(19), int64(50)}},
+		},
+		{
+			in:   "SELECT CampaignName, Clicks, Cost FROM CAMPAIGN_PERFORMANCE_REPORT ORDER BY Clicks DESC, Cost",
+			out:  "SELECT CampaignName, Clicks, Cost FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  report,
+			cols: []string{"Campaign", "Clicks", "Cost"},
+			data: [][]driver.Value{{"b", int64(21), nil}, {"c", int64(19), int64(50)}, {"a", int64(19), int64(100)}},
+		},
+		{
+			in:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT DURING YESTERDAY ORDER BY Clicks, Cost DESC LIMIT 1, 5",
+			out:  "SELECT CampaignName, Clicks, Cost FROM CAMPAIGN_PERFORMANCE_REPORT DURING YESTERDAY",
+			csv:  report,
+			cols: []string{"Campaign"},
+			data: [][]driver.Value{{"c"}, {"b"}},
+		},
+		{
+			in:   "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT WHERE Clicks > 1 LIMIT 2",
+			out:  "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT WHERE Clicks > 1",
+			csv:  "Campaign,Clicks\na,19\nb,21\nc,19\n",
+			cols: []string{"Campaign", "Clicks"},
+			data: [][]driver.Value{{"a", int64(19)}, {"b", int64(21)}},
+		},
+		{
+			in:   "SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT ORDER BY Clicks",
+			out:  "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  "",
+			cols: []string{},
+		},
+		{
+			in:   "SELECT CampaignName AS name, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT ORDER BY name DESC",
+			out:  "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  "Campaign,Clicks\na,19\nb,21\nc,19\n",
+			cols: []string{"name", "Clicks"},
+			data: [][]driver.Value{{"c", int64(19)}, {"b", int64(21)}, {"a", int64(19)}},
+		},
+		{
+			in:   "SELECT Clicks, COUNT(*), SUM(Cost) AS cost, AVG(Cost), MIN(CampaignName), MAX(CampaignName) FROM CAMPAIGN_PERFORMANCE_REPORT GROUP BY Clicks",
+			out:  "SELECT Clicks, Cost, CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  "Clicks,Cost,Campaign\n19,100,a\n21,--,b\n19,50,c\n",
+			cols: []string{"Clicks", "COUNT(*)", "cost", "AVG(Cost)", "MIN(CampaignName)", "MAX(CampaignName)"},
+			data: [][]driver.Value{
+				{int64(19), int64(2), int64(150), float64(75), "a", "c"},
+				{int64(21), int64(1), nil, nil, "b", "b"},
+			},
+		},
+		{
+			in:   "SELECT Clicks AS c, COUNT(CampaignName) FROM CAMPAIGN_PERFORMANCE_REPORT GROUP BY c HAVING SUM(Cost) > 50 ORDER BY c DESC",
+			out:  "SELECT Clicks, CampaignName, Cost FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  "Clicks,Campaign,Cost\n19,a,100\n21,b,--\n19,c,50\n",
+			cols: []string{"c", "COUNT(CampaignName)"},
+			data: [][]driver.Value{{int64(19), int64(2)}},
+		},
+		{
+			in:   "SELECT CampaignName, SUM(Clicks) AS clicks FROM CAMPAIGN_PERFORMANCE_REPORT DURING LAST_7_DAYS GROUP BY CampaignName ORDER BY clicks DESC, CampaignName LIMIT 2",
+			out:  "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT DURING LAST_7_DAYS",
+			csv:  "Campaign,Clicks\na,1\nb,2\na,3\nc,4\n",
+			cols: []string{"Campaign", "clicks"},
+			data: [][]driver.Value{{"a", int64(4)}, {"c", int64(4)}},
+		},
+		{
+			in:   "SELECT COUNT(*), SUM(Clicks) FROM CAMPAIGN_PERFORMANCE_REPORT",
+			out:  "SELECT Clicks FROM CAMPAIGN_PERFORMANCE_REPORT",
+			csv:  "",
+			cols: []string{"COUNT(*)", "SUM(Clicks)"},
+			data: [][]driver.Value{{int64(0), nil}},
+		},
+	}
+	for i, pt := range planTests {
+		p, cols, data := runPlan(t, pt.in, pt.csv)
+		if p.query != pt.out {
+			t.Errorf("%d. Expected %s as API query, received %s", i, pt.out, p.query)
+		}
+		if !reflect.DeepEqual(cols, pt.cols) {
+			t.Errorf("%d. Expected %v as columns, received %v", i, pt.cols, cols)
+		}
+		if !reflect.DeepEqual(data, pt.data) {
+			t.Errorf("%d. Expected %v as rows, received %v", i, pt.data, data)
+		}
+	}
+}
+
+// TestNewPlan_Error tests the errors of the local processing.
+func TestNewPlan_Error(t *testing.T) {
+	var planTests = []struct {
+		in  string
+		err string
+	}{
+		{
+			in:  "SELECT CampaignName, SUM(Clicks) FROM CAMPAIGN_PERFORMANCE_REPORT",
+			err: "QueryError.FIELD_NOT_GROUPED (CampaignName) at position 8",
+		},
+		{
+			in:  "SELECT CampaignId, SUM(Clicks) FROM CAMPAIGN_PERFORMANCE_REPORT GROUP BY CampaignId HAVING Cost > 1",
+			err: "QueryError.FIELD_NOT_GROUPED (Cost) at position 92",
+		},
+		{
+			in:  "SELECT CampaignId, SUM(Clicks) FROM CAMPAIGN_PERFORMANCE_REPORT GROUP BY CampaignId ORDER BY Cost",
+			err: "QueryError.FIELD_NOT_GROUPED (Cost) at position 94",
+		},
+	}
+	for i, pt := range planTests {
+		st, err := parser.Parse(pt.in)
+		if err != nil {
+			t.Fatalf("%d. Expected no error with %s, received %v", i, pt.in, err)
+		}
+		if _, err := newPlan(st.(*parser.SelectStmt), pt.in); err == nil || err.Error() != pt.err {
+			t.Errorf("%d. Expected %s as error with %s, received %v", i, pt.err, pt.in, err)
+		}
+	}
+}
+
+// TestCompare tests the function named compare.
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	var compareTests = []struct {
+		a, b driver.Value
+		c    int
+	}{
+		{nil, nil, 0},
+		{nil, int64(1), -1},
+		{int64(1), nil, 1},
+		{int64(1), int64(2), -1},
+		{int64(2), 1.5, 1},
+		{1.5, int64(2), -1},
+		{1.5, 1.5, 0},
+		{now, now.Add(time.Second), -1},
+		{now.Add(time.Second), now, 1},
+		{true, false, 1},
+		{false, true, -1},
+		{"a", "b", -1},
+		{"10", int64(9), -1},
+	}
+	for i, ct := range compareTests {
+		if c := compare(ct.a, ct.b); c != ct.c {
+			t.Errorf("%d. Expected %d comparing %v and %v, received %d", i, ct.c, ct.a, ct.b, c)
+		}
+	}
+}
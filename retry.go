@@ -0,0 +1,172 @@
+package awql
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy defines how the requests failing with a transient error are retried,
+// as the rate limits of the Adwords API or its temporary failures.
+// The delay between two attempts grows exponentially from MinBackoff to MaxBackoff,
+// with a random jitter. The delay requested by the Retry-After header, if any, is honored.
+// With a MaxAttempts lower than 2, the requests are never retried.
+// The zero properties are replaced by those of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff,
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used by default.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+// withDefaults returns the retry policy completed with the values of DefaultRetryPolicy
+// for its zero properties.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.MinBackoff == 0 {
+		p.MinBackoff = DefaultRetryPolicy.MinBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	return p
+}
+
+// retryableTypes lists the prefixes of the types of the API errors to retry.
+// @see https://developers.google.com/adwords/api/docs/common-errors
+var retryableTypes = []string{
+	"RateExceededError.",
+	"InternalApiError.",
+	"ReportDownloadError.ERROR_GETTING_RESPONSE_FROM_BACKEND",
+}
+
+// retryError represents a transient error.
+// When known, after is the delay to wait before the next attempt.
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+// Error returns the message of the transient error.
+func (e *retryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the transient error.
+func (e *retryError) Unwrap() error {
+	return e.err
+}
+
+// do calls fn until it succeeds, fails with an error that can not be retried
+// or the maximum number of attempts is reached.
+// The delay requested by an error is limited by MaxBackoff. If the next attempt would start
+// after the deadline of the context, the error of fn is returned without waiting.
+// It returns the last error of fn or the error of the context.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		re, ok := err.(*retryError)
+		if !ok {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return re.err
+		}
+		d := re.after
+		switch {
+		case d <= 0:
+			d = p.backoff(attempt)
+		case p.MaxBackoff > 0 && d > p.MaxBackoff:
+			d = p.MaxBackoff
+		}
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) < d {
+			return re.err
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// backoff returns the delay to wait after the given attempt, with an equal jitter:
+// half of the exponential delay is random.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for k := 1; k < attempt && d < p.MaxBackoff; k++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryable returns the error as transient if it can be retried.
+func retryable(err error) error {
+	e, ok := err.(*APIError)
+	if !ok {
+		return err
+	}
+	for _, t := range retryableTypes {
+		if strings.HasPrefix(e.Type, t) {
+			return &retryError{err: err}
+		}
+	}
+	return err
+}
+
+// retryableResponse returns the error of the response as transient if it can be retried,
+// with the delay requested by the response, if any.
+func retryableResponse(err error, resp *http.Response) error {
+	if retryableStatus(resp.StatusCode) {
+		return &retryError{err: err, after: retryAfter(resp.Header)}
+	}
+	return retryable(err)
+}
+
+// retryableStatus returns true if the request failing with this HTTP status code can be retried.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the response, 0 if none.
+// The header contains a number of seconds or a HTTP date.
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
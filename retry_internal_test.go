@@ -0,0 +1,170 @@
+package awql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRetryPolicy_Do tests the method do on the RetryPolicy struct.
+func TestRetryPolicy_Do(t *testing.T) {
+	var (
+		errFatal = errors.New("fatal")
+		p        = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	)
+	var doTests = []struct {
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{errs: []error{nil}, attempts: 1},
+		{errs: []error{errFatal}, attempts: 1, err: errFatal},
+		{errs: []error{&retryError{err: ErrBadNetwork}, nil}, attempts: 2},
+		{errs: []error{&retryError{err: ErrBadNetwork}, &retryError{err: ErrBadNetwork}, errFatal}, attempts: 3, err: errFatal},
+		{
+			errs:     []error{&retryError{err: ErrBadNetwork}, &retryError{err: ErrBadNetwork}, &retryError{err: ErrNoNetwork}},
+			attempts: 3,
+			err:      ErrNoNetwork,
+		},
+	}
+	for i, dt := range doTests {
+		var n int
+		err := p.do(context.Background(), func() error {
+			n++
+			return dt.errs[n-1]
+		})
+		if err != dt.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, dt.err, err)
+		}
+		if n != dt.attempts {
+			t.Errorf("%d. Expected %d attempts, received %d", i, dt.attempts, n)
+		}
+	}
+
+	// Never retries without attempts.
+	var n int
+	err := RetryPolicy{}.do(context.Background(), func() error {
+		n++
+		return &retryError{err: ErrBadNetwork}
+	})
+	if err != ErrBadNetwork || n != 1 {
+		t.Errorf("Expected one attempt, received %d with %v", n, err)
+	}
+
+	// Stops to wait once the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond, cancel)
+	err = DefaultRetryPolicy.do(ctx, func() error {
+		return &retryError{err: ErrBadNetwork, after: time.Second}
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected the cancellation of the context, received %v", err)
+	}
+
+	// Limits the requested delay by the maximum backoff.
+	n = 0
+	start := time.Now()
+	err = RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}.do(
+		context.Background(), func() error {
+			n++
+			return &retryError{err: ErrBadNetwork, after: time.Hour}
+		},
+	)
+	if d := time.Since(start); err != ErrBadNetwork || n != 2 || d > time.Second {
+		t.Errorf("Expected 2 attempts within the maximum backoff, received %d with %v after %v", n, err, d)
+	}
+
+	// Returns the last error without waiting past the deadline of the context.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	n = 0
+	start = time.Now()
+	err = DefaultRetryPolicy.do(ctx, func() error {
+		n++
+		return &retryError{err: ErrBadNetwork, after: 10 * time.Second}
+	})
+	if d := time.Since(start); err != ErrBadNetwork || n != 1 || d > 500*time.Millisecond {
+		t.Errorf("Expected the last error at once, received %v after %d attempts and %v", err, n, d)
+	}
+}
+
+// TestRetryPolicy_Backoff tests the method backoff on the RetryPolicy struct.
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	var backoffTests = []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 2, min: time.Second, max: 2 * time.Second},
+		{attempt: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{attempt: 4, min: 2500 * time.Millisecond, max: 5 * time.Second},
+		{attempt: 9, min: 2500 * time.Millisecond, max: 5 * time.Second},
+	}
+	for i, bt := range backoffTests {
+		for k := 0; k < 10; k++ {
+			if d := p.backoff(bt.attempt); d < bt.min || d > bt.max {
+				t.Errorf("%d. Expected a delay between %v and %v, received %v", i, bt.min, bt.max, d)
+			}
+		}
+	}
+}
+
+// TestRetryable tests the functions named retryable, retryableStatus and retryAfter.
+func TestRetryable(t *testing.T) {
+	var errorTests = []struct {
+		err   error
+		retry bool
+	}{
+		{err: ErrBadToken},
+		{err: &APIError{Type: "ReportDefinitionError.INVALID_FIELD_NAME_FOR_REPORT"}},
+		{err: &APIError{Type: "RateExceededError.RATE_EXCEEDED"}, retry: true},
+		{err: &APIError{Type: "InternalApiError.UNEXPECTED_INTERNAL_API_ERROR"}, retry: true},
+		{err: &APIError{Type: "ReportDownloadError.ERROR_GETTING_RESPONSE_FROM_BACKEND"}, retry: true},
+	}
+	for i, et := range errorTests {
+		_, ok := retryable(et.err).(*retryError)
+		if ok != et.retry {
+			t.Errorf("%d. Expected %v as retryable error with %v, received %v", i, et.retry, et.err, ok)
+		}
+	}
+	for code, retry := range map[int]bool{400: false, 401: false, 429: true, 500: true, 503: true, 504: true} {
+		if ok := retryableStatus(code); ok != retry {
+			t.Errorf("Expected %v as retryable status with %d, received %v", retry, code, ok)
+		}
+	}
+	h := make(http.Header)
+	if d := retryAfter(h); d != 0 {
+		t.Errorf("Expected no delay without header, received %v", d)
+	}
+	h.Set("Retry-After", "120")
+	if d := retryAfter(h); d != 2*time.Minute {
+		t.Errorf("Expected 2m0s as delay, received %v", d)
+	}
+	h.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if d := retryAfter(h); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("Expected one hour as delay, received %v", d)
+	}
+}
+
+// TestWithRetryPolicy_Defaults tests the default values of a partial retry policy.
+func TestWithRetryPolicy_Defaults(t *testing.T) {
+	var policyTests = []struct {
+		in, out RetryPolicy
+	}{
+		{in: RetryPolicy{}, out: DefaultRetryPolicy},
+		{in: RetryPolicy{MaxAttempts: 5}, out: RetryPolicy{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: 30 * time.Second}},
+		{in: RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond}, out: RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond, MaxBackoff: 30 * time.Second}},
+		{in: RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Second}, out: RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Second}},
+	}
+	for i, pt := range policyTests {
+		if c, _ := NewConnector(nil, WithRetryPolicy(pt.in)); c.retry != pt.out {
+			t.Errorf("%d. Expected %v as retry policy, received %v", i, pt.out, c.retry)
+		}
+		if c, _ := NewConnector(&Dsn{Retry: pt.in}); c.retry != pt.out {
+			t.Errorf("%d. Expected %v as retry policy of the data source name, received %v", i, pt.out, c.retry)
+		}
+	}
+}
@@ -2,22 +2,170 @@ package awql
 
 import (
 	"database/sql/driver"
+	"encoding/csv"
 	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	reportDateLayout = "Jan 2, 2006"
+	summaryLabel     = "Total"
 )
 
 // Rows is an iterator over an executed query's results.
+// Rows built with Data are iterated in memory, whereas those
+// of a report are read one by one from their source.
+// When the fields of the report are known, the values are converted to their Go type.
 type Rows struct {
 	Position, Size int
 	Data           [][]string
+
+	cols   []string
+	fields []*field
+	src    source
+	report *report
+}
+
+// ReportHeader is the header of a report, with its name and its date range.
+// The bounds of the date range are zero if they can not be parsed.
+type ReportHeader struct {
+	Name, DateRange string
+	Start, End      time.Time
+}
+
+// parseReportHeader parses the header of a report, like "CAMPAIGN_PERFORMANCE_REPORT (Jan 1, 2017-Jan 31, 2017)".
+func parseReportHeader(s string) *ReportHeader {
+	s = strings.TrimSpace(s)
+	h := &ReportHeader{Name: s}
+	i := strings.LastIndex(s, " (")
+	if i < 0 || !strings.HasSuffix(s, ")") {
+		return h
+	}
+	h.Name, h.DateRange = s[:i], s[i+2:len(s)-1]
+	if i = strings.Index(h.DateRange, "-"); i < 0 {
+		return h
+	}
+	start, err := time.Parse(reportDateLayout, strings.TrimSpace(h.DateRange[:i]))
+	if err != nil {
+		return h
+	}
+	end, err := time.Parse(reportDateLayout, strings.TrimSpace(h.DateRange[i+1:]))
+	if err != nil {
+		return h
+	}
+	h.Start, h.End = start, end
+
+	return h
+}
+
+// report contains the metadata of a report: its header and its summary,
+// both only available if required by the options of the connection.
+type report struct {
+	header  *ReportHeader
+	summary []driver.Value
+	// withSummary is true if the last record of the report is expected to be its summary.
+	withSummary bool
+}
+
+// source provides one by one the values of the rows.
+type source interface {
+	Next(dest []driver.Value) error
+	Close() error
+}
+
+// newCSVRows returns an iterator over the CSV records of the given reader.
+// If the column names are not given, the first record is used as header.
+// The fields are used to convert the values of the records.
+func newCSVRows(rc io.ReadCloser, cols []string, fields []*field) (*Rows, error) {
+	return newReportRows(rc, cols, fields, nil)
+}
+
+// newReportRows returns an iterator over the CSV records of the report downloaded with these options.
+// Unless skipped, the header of the report is parsed from its first record and its summary
+// from the last one, both excluded of the rows.
+func newReportRows(rc io.ReadCloser, cols []string, fields []*field, o *Opts) (*Rows, error) {
+	s := &csvSource{rd: csv.NewReader(rc), rc: rc, fields: fields}
+	r := &Rows{cols: cols, fields: fields, src: s}
+	if o != nil && (!o.SkipReportHeader || !o.SkipReportSummary) {
+		r.report = &report{withSummary: !o.SkipReportSummary}
+		s.report = r.report
+	}
+	// The records are read ahead to detect the summary of the report.
+	s.rd.ReuseRecord = r.report == nil || !r.report.withSummary
+
+	if o != nil && !o.SkipReportHeader {
+		// The header of the report has only one field.
+		s.rd.FieldsPerRecord = -1
+		rec, err := s.rd.Read()
+		switch {
+		case err == io.EOF:
+			if cols == nil {
+				r.cols = []string{}
+			}
+			return r, nil
+		case err != nil:
+			_ = r.Close()
+			return nil, err
+		}
+		r.report.header = parseReportHeader(rec[0])
+	}
+	if cols != nil {
+		return r, nil
+	}
+	rec, err := s.rd.Read()
+	switch {
+	case err == io.EOF:
+		// Empty report.
+		r.cols = []string{}
+	case err != nil:
+		_ = r.Close()
+		return nil, err
+	default:
+		r.cols = append([]string(nil), rec...)
+	}
+	return r, nil
+}
+
+// ReportHeader returns the header of the report, with its name and its date range.
+// It returns nil if the connection skips the header of the reports.
+// A statement run on multiple customer accounts returns the header of the first one.
+func (r *Rows) ReportHeader() *ReportHeader {
+	if r.report == nil {
+		return nil
+	}
+	return r.report.header
+}
+
+// Summary returns the values of the "Total" row of the report, once all its rows read.
+// It returns nil if the connection skips the summary of the reports, or if the rows
+// are aggregated or come from multiple customer accounts.
+func (r *Rows) Summary() []driver.Value {
+	if r.report == nil || r.report.summary == nil {
+		return nil
+	}
+	if len(r.report.summary) > len(r.cols) {
+		// Hides the columns only used locally.
+		return r.report.summary[:len(r.cols)]
+	}
+	return r.report.summary
 }
 
 // Close usual closes the rows iterator.
+// It releases the underlying source, if any.
 func (r *Rows) Close() error {
-	return nil
+	if r.src == nil {
+		return nil
+	}
+	return r.src.Close()
 }
 
 // Columns returns the names of the columns.
 func (r *Rows) Columns() []string {
+	if r.src != nil {
+		return r.cols
+	}
 	if r.Size == 0 {
 		return nil
 	}
@@ -26,14 +174,138 @@ func (r *Rows) Columns() []string {
 
 // Next is called to populate the next row of data into the provided slice.
 func (r *Rows) Next(dest []driver.Value) error {
+	if r.src != nil {
+		if err := r.src.Next(dest); err != nil {
+			return err
+		}
+		r.Position++
+		return nil
+	}
 	if r.Position == r.Size {
 		return io.EOF
 	}
 	// Converts slice of string into slice of interface, expected value of sql driver.
 	for k, v := range r.Data[r.Position] {
-		dest[k] = driver.Value(v)
+		dest[k] = r.value(k, v)
 	}
 	r.Position++
 
 	return nil
 }
+
+// ColumnTypeDatabaseTypeName returns the database system type name of the column.
+// An empty string is returned for a column of unknown type.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	if f := r.field(index); f != nil {
+		return f.DatabaseTypeName()
+	}
+	return ""
+}
+
+// ColumnTypeNullable returns true if the column may be null.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if f := r.field(index); f != nil {
+		return f.Nullable(), true
+	}
+	return false, false
+}
+
+// ColumnTypeScanType returns the Go type suitable for scanning into.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	if f := r.field(index); f != nil {
+		return f.ScanType()
+	}
+	return reflect.TypeOf("")
+}
+
+// field returns the report field of the column, nil if unknown.
+func (r *Rows) field(index int) *field {
+	return fieldAt(r.fields, index)
+}
+
+// value returns the value of the column as a value of its type, if known.
+func (r *Rows) value(index int, s string) driver.Value {
+	if f := r.field(index); f != nil {
+		return f.Value(s)
+	}
+	return s
+}
+
+// fieldAt returns the field at this index, nil if unknown.
+func fieldAt(fields []*field, index int) *field {
+	if index < 0 || index >= len(fields) {
+		return nil
+	}
+	return fields[index]
+}
+
+// csvSource decodes one by one the CSV records of a report.
+type csvSource struct {
+	rd     *csv.Reader
+	rc     io.Closer
+	fields []*field
+	report *report
+	// next is the record read ahead, and err the error of its reading.
+	next []string
+	err  error
+}
+
+// Close releases the report.
+func (s *csvSource) Close() error {
+	if s.rc == nil {
+		return nil
+	}
+	err := s.rc.Close()
+	s.rc = nil
+	return err
+}
+
+// Next decodes the next CSV record into the provided slice.
+func (s *csvSource) Next(dest []driver.Value) error {
+	if s.rc == nil {
+		// Closed report.
+		return io.EOF
+	}
+	rec, err := s.read()
+	if err != nil {
+		return err
+	}
+	s.decode(dest, rec)
+
+	return nil
+}
+
+// read returns the next record of the report, the summary excluded.
+// If the report ends with a summary, the records are read ahead to detect the last one.
+func (s *csvSource) read() ([]string, error) {
+	if s.report == nil || !s.report.withSummary {
+		return s.rd.Read()
+	}
+	rec, err := s.next, s.err
+	if rec == nil && err == nil {
+		rec, err = s.rd.Read()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.next, s.err = s.rd.Read()
+	if s.err == io.EOF && len(rec) > 0 && strings.TrimSpace(rec[0]) == summaryLabel {
+		s.report.summary = make([]driver.Value, len(rec))
+		s.decode(s.report.summary, rec)
+		return nil, io.EOF
+	}
+	return rec, nil
+}
+
+// decode converts the values of the record into the provided slice.
+func (s *csvSource) decode(dest []driver.Value, rec []string) {
+	for k := range dest {
+		if k >= len(rec) {
+			dest[k] = nil
+		} else if f := fieldAt(s.fields, k); f != nil {
+			dest[k] = f.Value(rec[k])
+		} else {
+			dest[k] = rec[k]
+		}
+	}
+}
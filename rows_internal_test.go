@@ -0,0 +1,197 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closeRecorder records the closing of the reader.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+// Close implements the io.Closer interface.
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+// TestNewCSVRows tests the streaming of CSV records with the function newCSVRows.
+func TestNewCSVRows(t *testing.T) {
+	var rowsTests = []struct {
+		csv           string
+		cols, columns []string
+		data          [][]string
+	}{
+		{csv: "", columns: []string{}},
+		{csv: "Day,Clicks\n", columns: []string{"Day", "Clicks"}},
+		{
+			csv:     "Day,Clicks\n2017-01-01,19\n2017-01-02,21\n",
+			columns: []string{"Day", "Clicks"},
+			data:    [][]string{{"2017-01-01", "19"}, {"2017-01-02", "21"}},
+		},
+		{
+			csv:     "2017-01-01,19\n2017-01-02,21\n",
+			cols:    []string{"Date", "Clicks"},
+			columns: []string{"Date", "Clicks"},
+			data:    [][]string{{"2017-01-01", "19"}, {"2017-01-02", "21"}},
+		},
+	}
+	for i, rt := range rowsTests {
+		rc := &closeRecorder{Reader: strings.NewReader(rt.csv)}
+		rs, err := newCSVRows(rc, rt.cols, nil)
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if c := rs.Columns(); !reflect.DeepEqual(c, rt.columns) {
+			t.Errorf("%d. Expected %v as columns, received %v", i, rt.columns, c)
+		}
+		var data [][]string
+		for {
+			dest := make([]driver.Value, len(rt.columns))
+			if err := rs.Next(dest); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%d. Expected no error, received %v", i, err)
+			}
+			row := make([]string, len(dest))
+			for k, v := range dest {
+				row[k] = v.(string)
+			}
+			data = append(data, row)
+		}
+		if !reflect.DeepEqual(data, rt.data) {
+			t.Errorf("%d. Expected %v as data, received %v", i, rt.data, data)
+		}
+		if err := rs.Close(); err != nil || !rc.closed {
+			t.Errorf("%d. Expected the stream to be closed without error, received %v", i, err)
+		}
+	}
+}
+
+// TestRows_Close tests that closing the rows ends the iteration.
+func TestRows_Close(t *testing.T) {
+	rs, err := newCSVRows(ioutil.NopCloser(strings.NewReader("Day\n2017-01-01\n")), nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatalf("Expected no error on close, received %v", err)
+	}
+	if err := rs.Next(make([]driver.Value, 1)); err != io.EOF {
+		t.Errorf("Expected io.EOF after closing, received %v", err)
+	}
+	if err := rs.Close(); err != nil {
+		t.Errorf("Expected no error when closing twice, received %v", err)
+	}
+}
+
+// TestParseReportHeader tests the parsing of the header of a report.
+func TestParseReportHeader(t *testing.T) {
+	var headerTests = []struct {
+		in  string
+		out *ReportHeader
+	}{
+		{in: "CAMPAIGN_PERFORMANCE_REPORT", out: &ReportHeader{Name: "CAMPAIGN_PERFORMANCE_REPORT"}},
+		{
+			in:  "CAMPAIGN_PERFORMANCE_REPORT (All Time)",
+			out: &ReportHeader{Name: "CAMPAIGN_PERFORMANCE_REPORT", DateRange: "All Time"},
+		},
+		{
+			in: "CAMPAIGN_PERFORMANCE_REPORT (Jan 1, 2017-Jan 31, 2017)",
+			out: &ReportHeader{
+				Name:      "CAMPAIGN_PERFORMANCE_REPORT",
+				DateRange: "Jan 1, 2017-Jan 31, 2017",
+				Start:     time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+				End:       time.Date(2017, time.January, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for i, ht := range headerTests {
+		if h := parseReportHeader(ht.in); !reflect.DeepEqual(h, ht.out) {
+			t.Errorf("%d. Expected %v as header, received %v", i, ht.out, h)
+		}
+	}
+}
+
+// TestNewReportRows tests the header and the summary of a report, excluded of its rows.
+func TestNewReportRows(t *testing.T) {
+	const report = "\"CAMPAIGN_PERFORMANCE_REPORT (Jan 1, 2017-Jan 31, 2017)\"\n" +
+		"Campaign,Clicks\nc1,19\nc2,21\nTotal,40\n"
+	var rowsTests = []struct {
+		opts    *Opts
+		columns []string
+		data    [][]driver.Value
+		header  *ReportHeader
+		summary []driver.Value
+	}{
+		{
+			opts:    &Opts{SkipReportHeader: true, SkipReportSummary: false},
+			columns: []string{"Campaign", "Clicks"},
+			data:    [][]driver.Value{{"c1", int64(19)}, {"c2", int64(21)}},
+			summary: []driver.Value{"Total", int64(40)},
+		},
+		{
+			opts:    &Opts{SkipReportHeader: false, SkipReportSummary: true},
+			columns: []string{"Campaign", "Clicks"},
+			data:    [][]driver.Value{{"c1", int64(19)}, {"c2", int64(21)}, {"Total", int64(40)}},
+			header: &ReportHeader{
+				Name:      "CAMPAIGN_PERFORMANCE_REPORT",
+				DateRange: "Jan 1, 2017-Jan 31, 2017",
+				Start:     time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+				End:       time.Date(2017, time.January, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			opts:    &Opts{},
+			columns: []string{"Campaign", "Clicks"},
+			data:    [][]driver.Value{{"c1", int64(19)}, {"c2", int64(21)}},
+			header: &ReportHeader{
+				Name:      "CAMPAIGN_PERFORMANCE_REPORT",
+				DateRange: "Jan 1, 2017-Jan 31, 2017",
+				Start:     time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+				End:       time.Date(2017, time.January, 31, 0, 0, 0, 0, time.UTC),
+			},
+			summary: []driver.Value{"Total", int64(40)},
+		},
+	}
+	fields := lookupFields(APIVersion, "CAMPAIGN_PERFORMANCE_REPORT", []string{"CampaignName", "Clicks"})
+	for i, rt := range rowsTests {
+		in := report
+		if rt.opts.SkipReportHeader {
+			in = in[strings.Index(in, "\n")+1:]
+		}
+		rs, err := newReportRows(ioutil.NopCloser(strings.NewReader(in)), nil, fields, rt.opts)
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if c := rs.Columns(); !reflect.DeepEqual(c, rt.columns) {
+			t.Errorf("%d. Expected %v as columns, received %v", i, rt.columns, c)
+		}
+		if h := rs.ReportHeader(); !reflect.DeepEqual(h, rt.header) {
+			t.Errorf("%d. Expected %v as header, received %v", i, rt.header, h)
+		}
+		var data [][]driver.Value
+		for {
+			dest := make([]driver.Value, len(rt.columns))
+			if err := rs.Next(dest); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%d. Expected no error, received %v", i, err)
+			}
+			data = append(data, dest)
+		}
+		if !reflect.DeepEqual(data, rt.data) {
+			t.Errorf("%d. Expected %v as data, received %v", i, rt.data, data)
+		}
+		if s := rs.Summary(); !reflect.DeepEqual(s, rt.summary) {
+			t.Errorf("%d. Expected %v as summary, received %v", i, rt.summary, s)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package awql_test
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	awql "github.com/rvflash/awql-driver"
 )
@@ -52,3 +55,50 @@ func TestAwqlRows_Next(t *testing.T) {
 		}
 	}
 }
+
+// TestRows_ColumnTypes tests the typed values of a report.
+func TestRows_ColumnTypes(t *testing.T) {
+	dsn := &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(newClient(
+		http.StatusOK,
+		"Day,Campaign,Impressions,Cost,CTR,Unknown\n2017-01-31,Rv,19,1230000,12.34%,--\n",
+		nil,
+	))))
+	defer db.Close()
+
+	rs, err := db.Query("SELECT Date, CampaignName, Impressions, Cost, Ctr, Oops FROM CAMPAIGN_PERFORMANCE_REPORT")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer rs.Close()
+
+	cts, err := rs.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Expected no error with the column types, received %v", err)
+	}
+	var names []string
+	for _, ct := range cts {
+		names = append(names, ct.DatabaseTypeName())
+	}
+	if exp := []string{"DATE", "STRING", "LONG", "MONEY", "DOUBLE", ""}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("Expected %v as column types, received %v", exp, names)
+	}
+	if n, ok := cts[2].Nullable(); n || !ok {
+		t.Errorf("Expected a not nullable column for the impressions, received %v", n)
+	}
+	if !rs.Next() {
+		t.Fatalf("Expected one row, received none with %v", rs.Err())
+	}
+	var (
+		day        time.Time
+		name, oops string
+		impr, cost int64
+		ctr        float64
+	)
+	if err := rs.Scan(&day, &name, &impr, &cost, &ctr, &oops); err != nil {
+		t.Fatalf("Expected no error when scanning, received %v", err)
+	}
+	if !day.Equal(time.Date(2017, 1, 31, 0, 0, 0, 0, time.UTC)) || name != "Rv" || impr != 19 || cost != 1230000 || ctr != 12.34 || oops != "--" {
+		t.Errorf("Unexpected values %v, %v, %v, %v, %v, %v", day, name, impr, cost, ctr, oops)
+	}
+}
@@ -0,0 +1,89 @@
+package awql
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+const msgUnknownReport = "unknown report"
+
+// lookupReports returns the definitions of the reports for this API version, sorted by name.
+func lookupReports(version string) []*reportDef {
+	catalog.once.Do(loadCatalog)
+	rs := make([]*reportDef, 0, len(catalog.reports[version]))
+	for _, r := range catalog.reports[version] {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Name < rs[j].Name
+	})
+	return rs
+}
+
+// showTables returns the names of the reports of this API version, matching the pattern if any.
+func showTables(version string, st *parser.ShowTablesStmt) *Rows {
+	var data [][]driver.Value
+	for _, r := range lookupReports(version) {
+		if like(st.Like, r.Name) {
+			data = append(data, []driver.Value{r.Name})
+		}
+	}
+	return &Rows{cols: []string{"Tables_in_" + version}, src: &valuesSource{data: data}}
+}
+
+// describe returns the fields of the report of this API version, matching the pattern if any.
+// The full description adds their display name. An unknown report is returned as QueryError.
+func describe(q, version string, st *parser.DescribeStmt) (*Rows, error) {
+	r, ok := lookupReport(version, st.Table.Name)
+	if !ok {
+		return nil, newSyntaxError(parser.NewError(q, msgUnknownReport, st.Table.Name, st.Table.Offset))
+	}
+	cols := []string{"Field", "Type", "Null", "Behavior"}
+	if st.Full {
+		cols = append(cols, "DisplayName")
+	}
+	var data [][]driver.Value
+	for _, f := range r.Fields {
+		if !like(st.Like, f.Name) {
+			continue
+		}
+		null := "NO"
+		if f.Nullable() {
+			null = "YES"
+		}
+		row := []driver.Value{f.Name, f.DatabaseTypeName(), null, f.Behavior}
+		if st.Full {
+			row = append(row, f.DisplayName)
+		}
+		data = append(data, row)
+	}
+	return &Rows{cols: cols, src: &valuesSource{data: data}}, nil
+}
+
+// like returns true if the name matches the pattern of the LIKE clause, whatever its case.
+// As in SQL, % matches any sequence of characters and _ any single character.
+// Without pattern, any name matches.
+func like(pattern parser.Value, name string) bool {
+	if pattern == nil {
+		return true
+	}
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, r := range parser.Unquote(pattern.String()) {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	ok, _ := regexp.MatchString(b.String(), name)
+	return ok
+}
@@ -0,0 +1,83 @@
+package awql_test
+
+import (
+	"database/sql"
+	"net/http"
+	"reflect"
+	"testing"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// TestShowTables tests the statements describing the reports, answered without any request.
+func TestShowTables(t *testing.T) {
+	var rqs []*http.Request
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(newClient(http.StatusOK, "", &rqs)),
+	))
+	defer db.Close()
+
+	var showTests = []struct {
+		q    string
+		cols []string
+		data [][]string
+		err  string
+	}{
+		{
+			q:    "SHOW TABLES LIKE 'a%'",
+			cols: []string{"Tables_in_v201809"},
+			data: [][]string{{"ACCOUNT_PERFORMANCE_REPORT"}, {"ADGROUP_PERFORMANCE_REPORT"}, {"AD_PERFORMANCE_REPORT"}, {"AUTOMATIC_PLACEMENTS_PERFORMANCE_REPORT"}},
+		},
+		{
+			q:    "SHOW TABLES LIKE 'CAMPAIGN_%'",
+			cols: []string{"Tables_in_v201809"},
+			data: [][]string{{"CAMPAIGN_PERFORMANCE_REPORT"}},
+		},
+		{
+			q:    "DESCRIBE CAMPAIGN_PERFORMANCE_REPORT",
+			cols: []string{"Field", "Type", "Null", "Behavior"},
+		},
+		{
+			q:    "SHOW FULL COLUMNS FROM campaign_performance_report LIKE 'campaign%'",
+			cols: []string{"Field", "Type", "Null", "Behavior", "DisplayName"},
+			data: [][]string{
+				{"CampaignId", "LONG", "YES", "ATTRIBUTE", "Campaign ID"},
+				{"CampaignName", "STRING", "YES", "ATTRIBUTE", "Campaign"},
+				{"CampaignStatus", "ENUM", "YES", "ATTRIBUTE", "Campaign state"},
+				{"CampaignTrialType", "ENUM", "YES", "ATTRIBUTE", "Campaign Trial Type"},
+			},
+		},
+		{
+			q:    "SHOW COLUMNS FROM CAMPAIGN_PERFORMANCE_REPORT LIKE 'Clicks'",
+			cols: []string{"Field", "Type", "Null", "Behavior"},
+			data: [][]string{{"Clicks", "LONG", "NO", "METRIC"}},
+		},
+		{q: "DESCRIBE UNKNOWN_REPORT", err: "QueryError.UNKNOWN_REPORT (UNKNOWN_REPORT) at position 10"},
+	}
+	for i, st := range showTests {
+		cols, data, err := queryAccounts(db, st.q)
+		if st.err != "" {
+			if err == nil || err.Error() != st.err {
+				t.Errorf("%d. Expected %s as error, received %v", i, st.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if !reflect.DeepEqual(cols, st.cols) {
+			t.Errorf("%d. Expected %v as columns, received %v", i, st.cols, cols)
+		}
+		if st.data == nil {
+			if len(data) == 0 {
+				t.Errorf("%d. Expected fields, received none", i)
+			}
+		} else if !reflect.DeepEqual(data, st.data) {
+			t.Errorf("%d. Expected %v as rows, received %v", i, st.data, data)
+		}
+	}
+	if len(rqs) != 0 {
+		t.Errorf("Expected no request, received %d", len(rqs))
+	}
+}
@@ -1,19 +1,19 @@
 package awql
 
 import (
+	"context"
 	"database/sql/driver"
-	"encoding/csv"
-	"fmt"
 	"hash/fnv"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/rvflash/awql-driver/parser"
 )
 
 const (
@@ -23,37 +23,132 @@ const (
 )
 
 // Stmt is a prepared statement.
+// SrcQuery is the query as prepared, never modified by the binding of the arguments.
 type Stmt struct {
 	Db       *Conn
 	SrcQuery string
+
+	stmt   parser.Statement
+	parsed string
+	bound  string
 }
 
 // Bind applies the required argument replacements on the query.
+// Only the placeholders are replaced, the question marks in string literals are kept.
+// The source query is kept as it, see String to get the bound query.
 func (s *Stmt) Bind(args []driver.Value) error {
-	if na := s.NumInput(); len(args) < na {
-		// Number of placements to replace exceeds the number of inputs.
-		return ErrQueryBinding
+	_, err := s.bind(namedValues(args))
+	return err
+}
+
+// version returns the API version of the connection, the default one without connection.
+func (s *Stmt) version() string {
+	if s.Db == nil || s.Db.opts == nil {
+		return APIVersion
+	}
+	return s.Db.opts.Version
+}
+
+// namedValues returns the arguments as unnamed values, in order.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	vs := make([]driver.NamedValue, len(args))
+	for k, v := range args {
+		vs[k] = driver.NamedValue{Ordinal: k + 1, Value: v}
+	}
+	return vs
+}
+
+// bind returns the query with its placeholders replaced by the arguments.
+// The placeholders ? take the unnamed arguments in order, whereas the named parameters,
+// as :name or @name, take the argument of the same name.
+func (s *Stmt) bind(args []driver.NamedValue) (string, error) {
+	if s.SrcQuery == "" {
+		// Nothing to bind.
+		return "", nil
 	}
-	q := s.SrcQuery
-	for _, rv := range args {
+	st, err := s.parse()
+	if err != nil {
+		return "", err
+	}
+	var (
+		q     strings.Builder
+		last  int
+		pos   []driver.Value
+		named = make(map[string]driver.Value)
+	)
+	for _, a := range args {
+		if a.Name == "" {
+			pos = append(pos, a.Value)
+		} else {
+			named[a.Name] = a.Value
+		}
+	}
+	for _, p := range st.Params() {
+		var (
+			rv driver.Value
+			ok bool
+		)
+		if p.Name == "" {
+			if ok = len(pos) > 0; ok {
+				rv, pos = pos[0], pos[1:]
+			}
+		} else {
+			rv, ok = named[p.Name[1:]]
+		}
+		if !ok {
+			// Missing argument for this placeholder.
+			return "", ErrQueryBinding
+		}
 		var v string
-		switch rv.(type) {
-		case float64, float32:
-			// Decimal point
-			v = fmt.Sprintf("%f", rv)
-		case int64, int:
-			// Decimal (base 10)
-			v = fmt.Sprintf("%d", rv)
-		case bool:
-			// TRUE or FALSE
-			v = strings.ToUpper(fmt.Sprintf("%t", rv))
-		default:
-			// Double-quoted string safely escaped
-			v = fmt.Sprintf("%q", rv)
+		if d, ok := rv.(DateRange); ok {
+			sel, _ := st.(*parser.SelectStmt)
+			v, err = bindDateRange(s.SrcQuery, s.version(), sel, p, d)
+		} else {
+			v, err = literal(rv)
+		}
+		if err != nil {
+			return "", err
 		}
-		q = strings.Replace(q, "?", v, 1)
+		q.WriteString(s.SrcQuery[last:p.Offset])
+		q.WriteString(v)
+		last = p.Offset + len(p.String())
+	}
+	q.WriteString(s.SrcQuery[last:])
+	if err := checkBoundDuring(q.String(), s.version(), st); err != nil {
+		return "", err
 	}
-	s.SrcQuery = q
+	s.bound = q.String()
+
+	return s.bound, nil
+}
+
+// CheckNamedValue accepts the slices as arguments, bound as list of values, as with the IN operator,
+// and the date ranges of the DURING clause.
+// Any other value is converted by the default converter of the database/sql package.
+func (s *Stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+// checkNamedValue converts each element of a slice argument with the default converter.
+// A DateRange is kept as it. It returns driver.ErrSkip for any other value.
+func checkNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(DateRange); ok {
+		// Bound as it on the DURING clause.
+		return nil
+	}
+	rv := reflect.ValueOf(nv.Value)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return driver.ErrSkip
+	}
+	vs := make([]driver.Value, rv.Len())
+	for k := range vs {
+		v, err := driver.DefaultParameterConverter.ConvertValue(rv.Index(k).Interface())
+		if err != nil {
+			return err
+		}
+		vs[k] = v
+	}
+	nv.Value = vs
 
 	return nil
 }
@@ -71,127 +166,275 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 // Hash returns a hash that represents the statement.
 // Of course, the binding must have already been done to make sense.
 func (s *Stmt) Hash() (string, error) {
-	if s.SrcQuery == "" {
+	q := s.String()
+	if q == "" {
 		return "", ErrQuery
 	}
 	h := fnv.New64()
-	if _, err := h.Write([]byte(strings.ToLower(s.SrcQuery))); err != nil {
+	if _, err := h.Write([]byte(strings.ToLower(q))); err != nil {
 		return "", err
 	}
 	return strconv.FormatUint(h.Sum64(), 10), nil
 }
 
-// NumInput returns the number of placeholder parameters.
+// NumInput returns the number of placeholder parameters, a named parameter being counted once.
+// It returns -1 if the query is invalid, the error being returned by the execution.
 func (s *Stmt) NumInput() int {
-	return strings.Count(s.SrcQuery, "?")
+	if s.SrcQuery == "" {
+		return 0
+	}
+	st, err := s.parse()
+	if err != nil {
+		return -1
+	}
+	var (
+		n     int
+		names = make(map[string]bool)
+	)
+	for _, p := range st.Params() {
+		if p.Name == "" || !names[p.Name[1:]] {
+			n++
+		}
+		if p.Name != "" {
+			names[p.Name[1:]] = true
+		}
+	}
+	return n
+}
+
+// String returns the query bound with the last arguments, or the source query if not yet bound.
+func (s *Stmt) String() string {
+	if s.bound != "" {
+		return s.bound
+	}
+	return s.SrcQuery
+}
+
+// parse returns the statement of the source query.
+// The parsing is only done once for a query.
+func (s *Stmt) parse() (parser.Statement, error) {
+	if s.stmt != nil && s.parsed == s.SrcQuery {
+		return s.stmt, nil
+	}
+	st, err := parseStatement(s.SrcQuery)
+	if err != nil {
+		return nil, err
+	}
+	s.stmt, s.parsed = st, s.SrcQuery
+
+	return s.stmt, nil
+}
+
+// parseStatement parses the query, any syntax error being returned as QueryError.
+func parseStatement(q string) (parser.Statement, error) {
+	st, err := parser.Parse(q)
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok {
+			return nil, newSyntaxError(e)
+		}
+		return nil, err
+	}
+	return st, nil
 }
 
 // Query sends request to Google Adwords API and retrieves its content.
 func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.query(context.Background(), namedValues(args))
+}
+
+// QueryContext sends request to Google Adwords API and retrieves its content.
+// The context cancels the download of the report and bounds its duration.
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.query(ctx, args)
+}
+
+// query binds the args on the query and downloads the report.
+// The statements describing the reports are answered by the catalog of the API version.
+func (s *Stmt) query(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	// Binds all the args on the query
-	if err := s.Bind(args); err != nil {
+	bq, err := s.bind(args)
+	if err != nil {
 		return nil, err
 	}
-	// Saves response in a file named with the hash64 of the query.
-	f, err := s.filePath()
+	ps, err := s.parse()
 	if err != nil {
 		return nil, err
 	}
-	// Downloads the report
-	if err := s.download(f); err != nil {
+	if bq != s.SrcQuery {
+		if ps, err = parseStatement(bq); err != nil {
+			return nil, err
+		}
+	}
+	var st *parser.SelectStmt
+	switch v := ps.(type) {
+	case *parser.ShowTablesStmt:
+		return showTables(s.version(), v), nil
+	case *parser.DescribeStmt:
+		return describe(bq, s.version(), v)
+	case *parser.SelectStmt:
+		st = v
+	}
+	if s.Db.closed() {
+		// The pool discards the connection and retries with another one.
+		return nil, driver.ErrBadConn
+	}
+	ids, err := s.Db.customers(ctx)
+	if err != nil {
+		return nil, s.Db.checkErr(err)
+	}
+	o, err := queryOpts(ctx, s.Db.opts, st, bq)
+	if err != nil {
 		return nil, err
 	}
-	// Parse the CSV report.
-	d, err := os.Open(f)
+	q := bq
+	if strings.Contains(q, "/*") {
+		// Removes the comments, unknown to Adwords API.
+		q = st.String()
+	}
+	if s.Db.fanOut() && !st.Aggregated() && fieldIndex(st.FieldNames(), customerIDField) < 0 {
+		// Identifies the account of each row.
+		st = withCustomerID(st)
+		q = st.String()
+	}
+	p, err := newPlan(st, q)
 	if err != nil {
 		return nil, err
 	}
-	defer d.Close()
+	p.opts = o
 
-	rs, err := csv.NewReader(d).ReadAll()
+	var rs *Rows
+	if s.Db.fanOut() {
+		rs, err = s.fanOutRows(ctx, ids, st, p)
+	} else {
+		rs, err = s.rows(ctx, ids[0], st, p)
+	}
+	if err != nil {
+		return nil, s.Db.checkErr(err)
+	}
+	return p.run(rs)
+}
+
+// rows fetches the report of the customer account and returns its rows.
+func (s *Stmt) rows(ctx context.Context, id string, st *parser.SelectStmt, p *plan) (*Rows, error) {
+	d, err := s.fetch(ctx, id, p.query, p.opts)
 	if err != nil {
 		return nil, err
 	}
-	// Starts the index to 1 in order to ignore the column header.
-	var offset int
-	if !s.Db.opts.SkipColumnHeader {
-		offset = 1
+	// Streams the CSV report.
+	var cols []string
+	if p.opts.SkipColumnHeader {
+		// The plan is shared by the queries of each customer account.
+		cols = append([]string(nil), p.fields...)
+	}
+	return newReportRows(d, cols, lookupFields(p.opts.Version, st.From.Name, p.fields), p.opts)
+}
+
+// fetch returns the report of the query q with these options on the customer account from the cache
+// if allowed and available. Otherwise, it downloads the report and caches it while reading it, if allowed.
+func (s *Stmt) fetch(ctx context.Context, id, q string, o *Opts) (io.ReadCloser, error) {
+	if s.Db.cache == nil {
+		return s.download(ctx, id, q, o)
+	}
+	mode, ttl := cacheControl(ctx, s.Db.cacheMode)
+	key := cacheKey(id, o, q)
+	if mode == CacheUse {
+		if d, ok := s.Db.cache.get(key, ttl); ok {
+			return d, nil
+		}
+	}
+	d, err := s.download(ctx, id, q, o)
+	if err != nil || mode == CacheBypass {
+		return d, err
 	}
-	if l := len(rs); l > offset {
-		return &Rows{Size: l, Data: rs, Position: offset}, nil
+	c, err := s.Db.cache.put(key, d)
+	if err != nil {
+		// Unable to cache the report, only streams it.
+		return d, nil
+	}
+	return c, nil
+}
+
+// download calls Adwords API with the query q and these options on the customer account
+// and returns the body of its response.
+// The requests failing with a transient error are retried with the retry policy of the connection.
+// The download, including the retries and the reading of the body, can not exceed the apiTimeout duration.
+// Closing the body releases the resources associated with this timeout.
+func (s *Stmt) download(ctx context.Context, id, q string, o *Opts) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	var rc io.ReadCloser
+	err := s.Db.retry.do(ctx, func() (err error) {
+		rc, err = s.request(ctx, id, q, o)
+		return
+	})
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	return &Rows{}, nil
+	return &cancelReadCloser{ReadCloser: rc, cancel: cancel}, nil
 }
 
-// download calls Adwords API and saves response in a file.
-func (s *Stmt) download(name string) error {
-	rq, err := http.NewRequest(
-		"POST", apiURL+s.Db.opts.Version,
-		strings.NewReader(url.Values{"__rdquery": {s.SrcQuery}, "__fmt": {apiFmt}}.Encode()),
+// request sends the query q with these options on the customer account to Adwords API
+// and returns the body of its response.
+func (s *Stmt) request(ctx context.Context, id, q string, o *Opts) (io.ReadCloser, error) {
+	rq, err := http.NewRequestWithContext(
+		ctx, "POST", apiURL+o.Version,
+		strings.NewReader(url.Values{"__rdquery": {q}, "__fmt": {apiFmt}}.Encode()),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	s.Db.client.Timeout = apiTimeout
 
 	// @see https://developers.google.com/adwords/api/docs/guides/reporting#request_headers
 	rq.Header.Add("Content-Type", "application/x-www-form-urlencoded; param=value")
 	rq.Header.Add("Accept", "*/*")
-	rq.Header.Add("clientCustomerId", s.Db.adwordsID)
+	rq.Header.Add("clientCustomerId", id)
 	rq.Header.Add("developerToken", s.Db.developerToken)
-	rq.Header.Add("includeZeroImpressions", strconv.FormatBool(s.Db.opts.IncludeZeroImpressions))
-	rq.Header.Add("skipColumnHeader", strconv.FormatBool(s.Db.opts.SkipColumnHeader))
-	rq.Header.Add("skipReportHeader", strconv.FormatBool(s.Db.opts.SkipReportHeader))
-	rq.Header.Add("skipReportSummary", strconv.FormatBool(s.Db.opts.SkipReportSummary))
-	rq.Header.Add("useRawEnumValues", strconv.FormatBool(s.Db.opts.UseRawEnumValues))
+	rq.Header.Add("includeZeroImpressions", strconv.FormatBool(o.IncludeZeroImpressions))
+	rq.Header.Add("skipColumnHeader", strconv.FormatBool(o.SkipColumnHeader))
+	rq.Header.Add("skipReportHeader", strconv.FormatBool(o.SkipReportHeader))
+	rq.Header.Add("skipReportSummary", strconv.FormatBool(o.SkipReportSummary))
+	rq.Header.Add("useRawEnumValues", strconv.FormatBool(o.UseRawEnumValues))
 
 	// Uses access token to fetch report
-	if s.Db.oAuth != nil {
-		if err := s.Db.authenticate(); err != nil {
-			return ErrBadToken
+	if s.Db.tokens != nil {
+		a, err := s.Db.authorization(ctx)
+		if err != nil {
+			return nil, err
 		}
-		rq.Header.Add("Authorization", s.Db.oAuth.String())
+		rq.Header.Add("Authorization", a)
 	}
 
 	// Downloads the report
 	resp, err := s.Db.client.Do(rq)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// Network failure.
+		return nil, &retryError{err: wrapConnectionError(ErrBadNetwork, err)}
 	}
-	defer resp.Body.Close()
 
 	// Manages response in error
 	if resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case 0:
-			return ErrNoNetwork
-		case http.StatusBadRequest:
-			out, _ := ioutil.ReadAll(resp.Body)
-			return NewAPIError(out)
-		default:
-			return ErrBadNetwork
+		defer resp.Body.Close()
+		if resp.StatusCode == 0 {
+			return nil, ErrNoNetwork
 		}
+		out, _ := ioutil.ReadAll(resp.Body)
+		return nil, retryableResponse(newAPIError(resp.StatusCode, resp.Header.Get(requestIDHeader), out), resp)
 	}
-
-	// Saves response in a file
-	out, err := os.Create(name)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+	return resp.Body, nil
 }
 
-// filePath returns the file path to save the response of the query.
-// @example /tmp/awql16027257112758723916.csv
-func (s *Stmt) filePath() (string, error) {
-	hash, err := s.Hash()
-	if err != nil {
-		return "", nil
-	}
-	path := []string{"awql", hash, ".", strings.ToLower(apiFmt)}
+// cancelReadCloser cancels its context once closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
 
-	return filepath.Join(os.TempDir(), strings.Join(path, "")), nil
+// Close closes the reader and cancels the context.
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
 }
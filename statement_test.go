@@ -1,9 +1,15 @@
 package awql_test
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rvflash/awql-driver"
 )
@@ -35,16 +41,49 @@ func TestStmt_Bind(t *testing.T) {
 		{
 			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = ? AND CampaignName = ? AND Amount > ?"},
 			a: []driver.Value{12345678, "rv", 12.3},
-			q: `select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = 12345678 AND CampaignName = "rv" AND Amount > 12.300000`,
+			q: `select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = 12345678 AND CampaignName = "rv" AND Amount > 12.3`,
+		},
+		{
+			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignName = 'Who?' AND CampaignId = ?"},
+			a: []driver.Value{12345678},
+			q: `select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignName = 'Who?' AND CampaignId = 12345678`,
+		},
+		{
+			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId IN ? AND CampaignName IN ?"},
+			a: []driver.Value{[]driver.Value{int64(1), int64(2)}, []driver.Value{"a", `b"`}},
+			q: `select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId IN [1, 2] AND CampaignName IN ["a", "b\""]`,
+		},
+		{
+			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId IN ?"},
+			a: []driver.Value{[]driver.Value{}},
+			e: awql.ErrQueryBinding,
+		},
+		{
+			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = :id"},
+			a: []driver.Value{12345678},
+			e: awql.ErrQueryBinding,
+		},
+		{
+			s: &awql.Stmt{SrcQuery: "select Cost FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = ? ?"},
+			a: []driver.Value{12345678},
+			e: &awql.QueryError{Trigger: "?", Pos: 67},
 		},
 	}
 
 	for i, st := range stmtTests {
+		src := st.s.SrcQuery
 		err := st.s.Bind(st.a)
-		if !reflect.DeepEqual(err, st.e) {
+		if st.s.SrcQuery != src {
+			t.Errorf("%d. Expected the source query to be kept, received %s", i, st.s.SrcQuery)
+		}
+		if qe, ok := err.(*awql.QueryError); ok && st.e != nil {
+			if e := st.e.(*awql.QueryError); qe.Trigger != e.Trigger || qe.Pos != e.Pos {
+				t.Errorf("%d. Expected %v as binding error, received %v", i, st.e, err)
+			}
+		} else if !reflect.DeepEqual(err, st.e) {
 			t.Fatalf("%d. Expected %v as binding error, received %v", i, st.e, err)
-		} else if st.e == nil && st.s.SrcQuery != st.q {
-			t.Errorf("%d. Expected %s as query after binding, received %v", i, st.q, st.s.SrcQuery)
+		} else if st.e == nil && st.s.String() != st.q {
+			t.Errorf("%d. Expected %s as query after binding, received %v", i, st.q, st.s.String())
 		}
 	}
 }
@@ -96,6 +135,9 @@ func TestStmt_NumInput(t *testing.T) {
 		{s: &awql.Stmt{}},
 		{s: &awql.Stmt{SrcQuery: "select CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = ?"}, n: 1},
 		{s: &awql.Stmt{SrcQuery: "select CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = ? AND CampaignStatus = ?"}, n: 2},
+		{s: &awql.Stmt{SrcQuery: "select CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignName = '?' AND CampaignId = ?"}, n: 1},
+		{s: &awql.Stmt{SrcQuery: "select CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT where CampaignId = :id AND Id != @id AND Name = ?"}, n: 2},
+		{s: &awql.Stmt{SrcQuery: "select CampaignName FORM CAMPAIGN_PERFORMANCE_REPORT"}, n: -1},
 	}
 	for i, st := range stmtTests {
 		if st.s.NumInput() != st.n {
@@ -103,3 +145,95 @@ func TestStmt_NumInput(t *testing.T) {
 		}
 	}
 }
+
+// TestStmt_QueryContext tests the binding of named parameters and slices on a prepared statement executed twice.
+func TestStmt_QueryContext(t *testing.T) {
+	var rqs []*http.Request
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"},
+		awql.WithHTTPClient(newClient(http.StatusOK, "Campaign\nRv\n", &rqs)),
+	))
+	defer db.Close()
+
+	st, err := db.Prepare("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE CampaignId IN :ids AND CampaignStatus = @status AND Clicks > ?")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer st.Close()
+
+	var argsTests = []struct {
+		args []interface{}
+		q    string
+	}{
+		{
+			args: []interface{}{sql.Named("status", "ENABLED"), sql.Named("ids", []int{1, 2}), 10},
+			q:    `SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE CampaignId IN [1, 2] AND CampaignStatus = "ENABLED" AND Clicks > 10`,
+		},
+		{
+			args: []interface{}{5, sql.Named("ids", []string{"3"}), sql.Named("status", "PAUSED")},
+			q:    `SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT WHERE CampaignId IN ["3"] AND CampaignStatus = "PAUSED" AND Clicks > 5`,
+		},
+	}
+	for i, at := range argsTests {
+		var name string
+		if err := st.QueryRow(at.args...).Scan(&name); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		_ = rqs[i].ParseForm()
+		if q := rqs[i].PostForm.Get("__rdquery"); q != at.q {
+			t.Errorf("%d. Expected %s as query, received %s", i, at.q, q)
+		}
+	}
+}
+
+// TestStmt_QueryContext_APIError tests the errors of the responses of the Adwords API in error.
+func TestStmt_QueryContext_APIError(t *testing.T) {
+	const (
+		denied = "<reportDownloadError><ApiError><type>AuthorizationError.USER_PERMISSION_DENIED</type>" +
+			"<trigger></trigger><fieldPath></fieldPath></ApiError></reportDownloadError>"
+		rate = "<reportDownloadError><ApiError><type>RateExceededError.RATE_EXCEEDED</type>" +
+			"<trigger></trigger><fieldPath></fieldPath></ApiError></reportDownloadError>"
+	)
+	var errTests = []struct {
+		status   int
+		body     string
+		target   error
+		msg      string
+		attempts int
+	}{
+		{status: http.StatusUnauthorized, target: awql.ErrAPIAuth, msg: "401 Unauthorized", attempts: 1},
+		{status: http.StatusForbidden, body: denied, target: awql.ErrAPIAuth, msg: "AuthorizationError.USER_PERMISSION_DENIED", attempts: 1},
+		{status: http.StatusTooManyRequests, body: rate, target: awql.ErrAPIRateLimit, msg: "RateExceededError.RATE_EXCEEDED", attempts: 2},
+		{status: http.StatusInternalServerError, body: "<html>Oops</html>", target: awql.ErrAPIInternal, msg: "500 Internal Server Error", attempts: 2},
+	}
+	for i, et := range errTests {
+		var n int
+		db := sql.OpenDB(newConnector(
+			t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN"},
+			awql.WithHTTPClient(&http.Client{Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+				n++
+				return &http.Response{
+					StatusCode: et.status,
+					Header:     http.Header{"Requestid": {"00056d2b"}},
+					Body:       ioutil.NopCloser(strings.NewReader(et.body)),
+					Request:    rq,
+				}, nil
+			})}),
+			awql.WithRetryPolicy(awql.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		))
+		_, err := db.Query("SELECT CampaignName FROM CAMPAIGN_PERFORMANCE_REPORT")
+		var e *awql.APIError
+		switch {
+		case !errors.As(err, &e):
+			t.Errorf("%d. Expected an API error, received %v", i, err)
+		case e.StatusCode != et.status || e.RequestID != "00056d2b":
+			t.Errorf("%d. Expected the status %d and the request ID, received %d and %q", i, et.status, e.StatusCode, e.RequestID)
+		case !errors.Is(err, et.target) || err.Error() != et.msg:
+			t.Errorf("%d. Expected %s as %v, received %v", i, et.msg, et.target, err)
+		}
+		if n != et.attempts {
+			t.Errorf("%d. Expected %d attempts, received %d", i, et.attempts, n)
+		}
+		_ = db.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package awql
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TokenStore persists the access tokens, in order to reuse them after a restart of the process.
+// Load returns nil without error if no token is stored with the key.
+type TokenStore interface {
+	Load(key string) (*AuthToken, error)
+	Save(key string, tk *AuthToken) error
+}
+
+// NewFileTokenStore returns a TokenStore saving the access tokens in a JSON file,
+// only readable and writable by its owner. The directory of the file is created if necessary.
+func NewFileTokenStore(path string) TokenStore {
+	return &fileTokenStore{path: path}
+}
+
+type fileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Load implements the TokenStore interface.
+func (s *fileTokenStore) Load(key string) (*AuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return m[key], nil
+}
+
+// Save implements the TokenStore interface.
+// The expired tokens are removed from the file.
+func (s *fileTokenStore) Save(key string, tk *AuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.read()
+	if err != nil {
+		return err
+	}
+	for k, t := range m {
+		if t == nil || !t.Valid() {
+			delete(m, k)
+		}
+	}
+	m[key] = tk
+
+	return s.write(m)
+}
+
+// read returns the tokens of the file by key.
+func (s *fileTokenStore) read() (map[string]*AuthToken, error) {
+	m := make(map[string]*AuthToken)
+	d, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(d, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// write replaces the file by a new one with the tokens.
+func (s *fileTokenStore) write(m map[string]*AuthToken) error {
+	d, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// The temporary file is only readable and writable by its owner.
+	f, err := ioutil.TempFile(dir, "awql")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(d)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// storedTokenSource looks for a valid token in the store before retrieving a new one with its source.
+// The new tokens are saved in the store.
+type storedTokenSource struct {
+	key   string
+	store TokenStore
+	src   TokenSource
+}
+
+// Token implements the TokenSource interface.
+// As the store is optional, its failures are ignored.
+func (s *storedTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	if tk, err := s.store.Load(s.key); err == nil && tk != nil && tk.Valid() {
+		return tk, nil
+	}
+	tk, err := s.src.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.store.Save(s.key, tk)
+
+	return tk, nil
+}
+
+// refreshToken implements the tokenRefresher interface.
+// The new token replaces the stored one.
+func (s *storedTokenSource) refreshToken(ctx context.Context) (*AuthToken, error) {
+	tk, err := refreshToken(ctx, s.src)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.store.Save(s.key, tk)
+
+	return tk, nil
+}
+
+// sharedTokenSourceWithStore returns a SharedTokenSource using the store, if any,
+// before retrieving a new token with src.
+func sharedTokenSourceWithStore(key string, src TokenSource, store TokenStore) TokenSource {
+	if store != nil {
+		src = &storedTokenSource{key: key, store: store, src: src}
+	}
+	return SharedTokenSource(key, src)
+}
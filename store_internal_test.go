@@ -0,0 +1,104 @@
+package awql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileTokenStore tests the TokenStore returned by NewFileTokenStore.
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tokens", "awql.json")
+	s := NewFileTokenStore(path)
+	if tk, err := s.Load("k3y"); tk != nil || err != nil {
+		t.Fatalf("Expected no token without file, received %v with %v", tk, err)
+	}
+	var (
+		expired = &AuthToken{AccessToken: "ya29.E", TokenType: "Bearer", Expiry: time.Now().Add(-time.Hour)}
+		valid   = &AuthToken{AccessToken: "ya29.V", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	)
+	if err := s.Save("old", expired); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := s.Save("k3y", valid); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if m := fi.Mode().Perm(); m != 0600 {
+		t.Errorf("Expected 0600 as permissions of the file, received %v", m)
+	}
+
+	// Reads the file as after a restart.
+	s = NewFileTokenStore(path)
+	tk, err := s.Load("k3y")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if tk == nil || tk.String() != valid.String() || !tk.Expiry.Equal(valid.Expiry) {
+		t.Errorf("Expected %v as stored token, received %v", valid, tk)
+	}
+	if tk, _ := s.Load("old"); tk != nil {
+		t.Errorf("Expected the removal of the expired token, received %v", tk)
+	}
+
+	// Fails on invalid file.
+	if err := ioutil.WriteFile(path, []byte("oops"), 0600); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if _, err := s.Load("k3y"); err == nil {
+		t.Error("Expected an error with an invalid file")
+	}
+}
+
+// TestStoredTokenSource tests the method Token on the storedTokenSource struct.
+func TestStoredTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awql")
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		ctx   = context.Background()
+		store = NewFileTokenStore(filepath.Join(dir, "awql.json"))
+		src   = &blockTokenSource{release: make(chan struct{})}
+		ts    = &storedTokenSource{key: "k3y", store: store, src: src}
+	)
+	close(src.release)
+
+	// Retrieves then saves the new token.
+	tk, err := ts.Token(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if stored, _ := store.Load("k3y"); stored == nil || stored.AccessToken != tk.AccessToken {
+		t.Errorf("Expected the new token in the store, received %v", stored)
+	}
+	// Reads the stored token.
+	if _, err := ts.Token(ctx); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if src.n != 1 {
+		t.Errorf("Expected one retrieval of the token, received %d", src.n)
+	}
+
+	// Retrieves and saves a new token, regardless of the stored one.
+	if _, err := refreshToken(ctx, ts); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if src.n != 2 {
+		t.Errorf("Expected a new retrieval of the token, received %d", src.n)
+	}
+}
@@ -0,0 +1,82 @@
+package awql_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// memoryTokenStore is a TokenStore in memory.
+type memoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*awql.AuthToken
+	loads  int
+}
+
+// Load implements the awql.TokenStore interface.
+func (s *memoryTokenStore) Load(key string) (*awql.AuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	return s.tokens[key], nil
+}
+
+// Save implements the awql.TokenStore interface.
+func (s *memoryTokenStore) Save(key string, tk *awql.AuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = tk
+	return nil
+}
+
+// TestWithTokenStore tests the saving of the new access tokens in the store.
+func TestWithTokenStore(t *testing.T) {
+	var (
+		n      int
+		header string
+		store  = &memoryTokenStore{tokens: make(map[string]*awql.AuthToken)}
+	)
+	client := &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			body := "Account\nRv\n"
+			if rq.URL.Host == "accounts.google.com" {
+				n++
+				body = `{"access_token":"ya29.S","token_type":"Bearer","expires_in":3600}`
+			} else {
+				header = rq.Header.Get("Authorization")
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    rq,
+			}, nil
+		}),
+	}
+	dsn := &awql.Dsn{
+		AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN",
+		ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/St0r3d",
+	}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(client), awql.WithTokenStore(store)))
+	defer db.Close()
+
+	var name string
+	if err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if n != 1 || header != "Bearer ya29.S" {
+		t.Errorf("Expected the new token, received %s after %d token requests", header, n)
+	}
+	if store.loads != 1 || len(store.tokens) != 1 {
+		t.Fatalf("Expected one saved token after one load, received %d after %d", len(store.tokens), store.loads)
+	}
+	for _, tk := range store.tokens {
+		if tk.AccessToken != "ya29.S" || !tk.Valid() {
+			t.Errorf("Expected the new token in the store, received %v", tk)
+		}
+	}
+}
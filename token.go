@@ -0,0 +1,422 @@
+package awql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tokenURL            = "https://accounts.google.com/o/oauth2/token"
+	tokenTimeout        = time.Duration(4 * time.Second)
+	tokenExpiryDelta    = 10 * time.Second
+	tokenExpiryDuration = 60 * time.Minute
+)
+
+// TokenSource provides the OAuth access tokens used to call the Adwords API.
+// As the oauth2.TokenSource of the golang.org/x/oauth2 package, it allows to use
+// any secret manager or token broker. The context bounds the retrieval of a token.
+// A TokenSource can be shared by the connections, so it must be safe for concurrent use.
+type TokenSource interface {
+	Token(ctx context.Context) (*AuthToken, error)
+}
+
+// tokenRefresher is implemented by the token sources caching their tokens,
+// to retrieve a new access token, regardless of the cached one.
+type tokenRefresher interface {
+	refreshToken(ctx context.Context) (*AuthToken, error)
+}
+
+// refreshToken returns a new access token of the token source, bypassing its cache if any.
+func refreshToken(ctx context.Context, ts TokenSource) (*AuthToken, error) {
+	if r, ok := ts.(tokenRefresher); ok {
+		return r.refreshToken(ctx)
+	}
+	return ts.Token(ctx)
+}
+
+// refreshingTokenSource is a TokenSource always refreshing the tokens of its source.
+type refreshingTokenSource struct {
+	src TokenSource
+}
+
+// Token implements the TokenSource interface.
+func (s refreshingTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	return refreshToken(ctx, s.src)
+}
+
+// String returns a representation of the access token, as used in the Authorization header.
+func (t *AuthToken) String() string {
+	return t.TokenType + " " + t.AccessToken
+}
+
+// Valid returns true if the access token is not expired.
+// The delta in seconds is used to avoid delay expiration of the token.
+func (t *AuthToken) Valid() bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return !t.Expiry.Add(-tokenExpiryDelta).Before(time.Now())
+}
+
+// StaticTokenSource returns a TokenSource always returning the same access token.
+// Without expiry, the token never expires. Otherwise, it fails once expired.
+func StaticTokenSource(tk *AuthToken) TokenSource {
+	return &staticTokenSource{tk: tk}
+}
+
+type staticTokenSource struct {
+	tk *AuthToken
+}
+
+// Token implements the TokenSource interface.
+func (s *staticTokenSource) Token(_ context.Context) (*AuthToken, error) {
+	if s.tk == nil || s.tk.AccessToken == "" {
+		return nil, ErrBadToken
+	}
+	if !s.tk.Expiry.IsZero() && !s.tk.Valid() {
+		return nil, ErrBadToken
+	}
+	tk := *s.tk
+	return &tk, nil
+}
+
+// ReuseTokenSource returns a TokenSource returning the same token as long as it is valid,
+// starting with tk if not nil. Once expired, a new token is retrieved with src.
+func ReuseTokenSource(tk *AuthToken, src TokenSource) TokenSource {
+	s := &reuseTokenSource{src: src}
+	if tk != nil {
+		t := *tk
+		s.tk = &t
+	}
+	return s
+}
+
+type reuseTokenSource struct {
+	src TokenSource
+	mu  sync.Mutex
+	tk  *AuthToken
+}
+
+// Token implements the TokenSource interface.
+// The token is retrieved by one caller at a time.
+func (s *reuseTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tk == nil || !s.tk.Valid() {
+		tk, err := s.src.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.tk = tk
+	}
+	tk := *s.tk
+	return &tk, nil
+}
+
+// refreshToken implements the tokenRefresher interface.
+func (s *reuseTokenSource) refreshToken(ctx context.Context) (*AuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tk, err := refreshToken(ctx, s.src)
+	if err != nil {
+		return nil, err
+	}
+	s.tk = tk
+	t := *tk
+	return &t, nil
+}
+
+// RefreshTokenSource returns a TokenSource retrieving a new access token
+// with the refresh token of the keys on each call.
+// It is usually wrapped by ReuseTokenSource or SharedTokenSource.
+// If the HTTP client is nil, http.DefaultClient is used.
+func RefreshTokenSource(client *http.Client, key AuthKey) TokenSource {
+	return newRefreshTokenSource(client, key, DefaultRetryPolicy)
+}
+
+func newRefreshTokenSource(client *http.Client, key AuthKey, retry RetryPolicy) *refreshTokenSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &refreshTokenSource{client: client, key: key, retry: retry}
+}
+
+type refreshTokenSource struct {
+	client *http.Client
+	key    AuthKey
+	retry  RetryPolicy
+}
+
+// Token implements the TokenSource interface.
+// The token request, including its retries, can not exceed the tokenTimeout duration.
+func (s *refreshTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	if s.key.ClientID == "" {
+		// No client information to refresh the token.
+		return nil, ErrBadToken
+	}
+	ctx, cancel := context.WithTimeout(ctx, tokenTimeout)
+	defer cancel()
+
+	var d io.ReadCloser
+	err := s.retry.do(ctx, func() (err error) {
+		d, err = s.download(ctx)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retrieveToken(d)
+}
+
+// download calls Google Auth Api to retrieve an access token.
+// @example Google Token
+//
+//	{
+//	    "access_token": "ya29.ExaMple",
+//	    "token_type": "Bearer",
+//	    "expires_in": 60
+//	}
+func (s *refreshTokenSource) download(ctx context.Context) (io.ReadCloser, error) {
+	return postToken(ctx, s.client, tokenURL, url.Values{
+		"client_id":     {s.key.ClientID},
+		"client_secret": {s.key.ClientSecret},
+		"refresh_token": {s.key.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+}
+
+// postToken sends the form to the Google token endpoint u and returns the body of its response.
+func postToken(ctx context.Context, client *http.Client, u string, form url.Values) (io.ReadCloser, error) {
+	rq, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// Retrieves an access token
+	resp, err := client.Do(rq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// Network failure.
+		return nil, &retryError{err: wrapConnectionError(ErrBadNetwork, err)}
+	}
+	// Manages response in error
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == 0:
+			return nil, ErrNoNetwork
+		case resp.StatusCode == http.StatusBadRequest, resp.StatusCode == http.StatusUnauthorized:
+			// Rejected credentials, as a revoked refresh token.
+			e := &oauthError{status: resp.StatusCode}
+			_ = json.NewDecoder(resp.Body).Decode(e)
+			return nil, wrapConnectionError(ErrBadToken, e)
+		case retryableStatus(resp.StatusCode):
+			return nil, &retryError{err: ErrBadNetwork, after: retryAfter(resp.Header)}
+		default:
+			return nil, ErrBadNetwork
+		}
+	}
+	return resp.Body, nil
+}
+
+// oauthError represents the rejection of the credentials by the Google token endpoint,
+// as invalid_grant for a revoked refresh token. Its category is ErrAPIAuth.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	status      int
+}
+
+// Error returns the code of the error and its description, if any.
+func (e *oauthError) Error() string {
+	switch {
+	case e.Code == "":
+		return http.StatusText(e.status)
+	case e.Description == "":
+		return e.Code
+	default:
+		return e.Code + ": " + e.Description
+	}
+}
+
+// Is returns true if the target is ErrAPIAuth.
+func (e *oauthError) Is(target error) bool {
+	return target == ErrAPIAuth
+}
+
+// retrieveToken parses the JSON response in order to map it to a AuthToken.
+// An error occurs if the JSON is invalid.
+func retrieveToken(d io.ReadCloser) (*AuthToken, error) {
+	var tk struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresInSec int    `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+	}
+	defer d.Close()
+
+	err := json.NewDecoder(d).Decode(&tk)
+	if err != nil {
+		// Unable to parse the JSON response.
+		return nil, ErrBadToken
+	}
+	if tk.ExpiresInSec == 0 || tk.AccessToken == "" {
+		// Invalid format of the token.
+		return nil, ErrBadToken
+	}
+	return &AuthToken{
+		AccessToken: tk.AccessToken,
+		TokenType:   tk.TokenType,
+		Expiry:      time.Now().Add(time.Duration(tk.ExpiresInSec) * time.Second),
+	}, nil
+}
+
+// sharedTokens is the process-wide cache of the access tokens.
+var sharedTokens = &tokenCache{entries: make(map[string]*tokenEntry)}
+
+// SharedTokenSource returns a TokenSource sharing its tokens with all the token sources
+// of the process created with the same key. A new token is only retrieved with src
+// once the shared token expired, by one caller at a time, the concurrent callers
+// waiting for its result.
+func SharedTokenSource(key string, src TokenSource) TokenSource {
+	return &sharedTokenSource{key: key, src: src, cache: sharedTokens}
+}
+
+type sharedTokenSource struct {
+	key   string
+	src   TokenSource
+	cache *tokenCache
+}
+
+// Token implements the TokenSource interface.
+func (s *sharedTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	return s.cache.token(ctx, s.key, s.src)
+}
+
+// refreshToken implements the tokenRefresher interface.
+// The new token replaces the shared one.
+func (s *sharedTokenSource) refreshToken(ctx context.Context) (*AuthToken, error) {
+	s.cache.invalidate(s.key)
+	return s.cache.token(ctx, s.key, refreshingTokenSource{src: s.src})
+}
+
+// tokenKey returns a key identifying the credentials, without exposing them.
+func tokenKey(credentials ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(credentials, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// tokenCache is a mutex-protected cache of access tokens.
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]*tokenEntry
+}
+
+// tokenEntry contains the token of a key and the retrieval in progress of a new one, if any.
+type tokenEntry struct {
+	tk   *AuthToken
+	call *tokenCall
+}
+
+// tokenCall represents the retrieval of a token.
+// Once done, the channel is closed and the result is available.
+type tokenCall struct {
+	done chan struct{}
+	tk   *AuthToken
+	err  error
+}
+
+// entry returns the entry of the key, created if necessary.
+// The lock of the cache must be held.
+func (c *tokenCache) entry(key string) *tokenEntry {
+	e, ok := c.entries[key]
+	if !ok {
+		e = &tokenEntry{}
+		c.entries[key] = e
+	}
+	return e
+}
+
+// set caches the token of the key if it is valid and the cached one is not.
+func (c *tokenCache) set(key string, tk *AuthToken) {
+	if tk == nil || !tk.Valid() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.entry(key); e.tk == nil || !e.tk.Valid() {
+		t := *tk
+		e.tk = &t
+	}
+}
+
+// invalidate removes the cached token of the key.
+// A retrieval in progress is not affected.
+func (c *tokenCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entry(key).tk = nil
+}
+
+// token returns a copy of the valid token of the key or retrieves a new one with src.
+// Only one retrieval by key is in progress, the callers wait for its result or their cancellation.
+func (c *tokenCache) token(ctx context.Context, key string, src TokenSource) (*AuthToken, error) {
+	c.mu.Lock()
+	e := c.entry(key)
+	if e.tk != nil && e.tk.Valid() {
+		tk := *e.tk
+		c.mu.Unlock()
+		return &tk, nil
+	}
+	call := e.call
+	if call == nil {
+		// Retrieves the new token.
+		call = &tokenCall{done: make(chan struct{})}
+		e.call = call
+		go c.retrieve(e, call, src)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	tk := *call.tk
+	return &tk, nil
+}
+
+// retrieve gets a new token of the entry with src.
+// As its result is shared by all the callers, the retrieval does not depend on the context
+// of one of them and can not exceed the tokenTimeout duration.
+func (c *tokenCache) retrieve(e *tokenEntry, call *tokenCall, src TokenSource) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+	defer cancel()
+
+	call.tk, call.err = src.Token(ctx)
+
+	c.mu.Lock()
+	e.call = nil
+	if call.err == nil {
+		e.tk = call.tk
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
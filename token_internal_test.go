@@ -0,0 +1,165 @@
+package awql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockTokenSource counts the token retrievals, each of them waiting for the release.
+type blockTokenSource struct {
+	n       int32
+	release chan struct{}
+	err     error
+}
+
+// Token implements the TokenSource interface.
+func (s *blockTokenSource) Token(_ context.Context) (*AuthToken, error) {
+	atomic.AddInt32(&s.n, 1)
+	<-s.release
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &AuthToken{AccessToken: "ya29.A", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+// TestTokenCache_Token tests the method token on the tokenCache struct.
+func TestTokenCache_Token(t *testing.T) {
+	var cacheTests = []struct {
+		err error
+	}{
+		{err: nil},
+		{err: errors.New("oops")},
+	}
+	for i, ct := range cacheTests {
+		var (
+			wg    sync.WaitGroup
+			cache = &tokenCache{entries: make(map[string]*tokenEntry)}
+			src   = &blockTokenSource{release: make(chan struct{}), err: ct.err}
+			errs  = make([]error, 10)
+		)
+		for k := range errs {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				_, errs[k] = cache.token(context.Background(), "k3y", src)
+			}(k)
+		}
+		// Waits for the first retrieval before releasing it.
+		for atomic.LoadInt32(&src.n) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(src.release)
+		wg.Wait()
+
+		for k, err := range errs {
+			if err != ct.err {
+				t.Errorf("%d. Expected %v as error of the caller %d, received %v", i, ct.err, k, err)
+			}
+		}
+		if n := atomic.LoadInt32(&src.n); ct.err == nil && n != 1 {
+			t.Errorf("%d. Expected one retrieval of the token, received %d", i, n)
+		}
+		if _, err := cache.token(context.Background(), "k3y", src); err != ct.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, ct.err, err)
+		}
+	}
+
+	// Stops to wait once the context is canceled.
+	cache := &tokenCache{entries: make(map[string]*tokenEntry)}
+	src := &blockTokenSource{release: make(chan struct{})}
+	go func() { _, _ = cache.token(context.Background(), "k3y", src) }()
+	for atomic.LoadInt32(&src.n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := cache.token(ctx, "k3y", src); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline of the context, received %v", err)
+	}
+	close(src.release)
+}
+
+// ctxTokenSource waits for the release or the cancellation of its context.
+type ctxTokenSource struct {
+	blockTokenSource
+}
+
+// Token implements the TokenSource interface.
+func (s *ctxTokenSource) Token(ctx context.Context) (*AuthToken, error) {
+	atomic.AddInt32(&s.n, 1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &AuthToken{AccessToken: "ya29.A", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+// TestTokenCache_Token_Canceled tests the retrieval of the token once its first caller is canceled.
+func TestTokenCache_Token_Canceled(t *testing.T) {
+	var (
+		cache = &tokenCache{entries: make(map[string]*tokenEntry)}
+		src   = &ctxTokenSource{blockTokenSource{release: make(chan struct{})}}
+		errs  = make(chan error, 1)
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := cache.token(ctx, "k3y", src)
+		errs <- err
+	}()
+	for atomic.LoadInt32(&src.n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.token(context.Background(), "k3y", src)
+		done <- err
+	}()
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Expected the cancellation of the first caller, received %v", err)
+	}
+	close(src.release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the token for the other caller, received %v", err)
+	}
+	if n := atomic.LoadInt32(&src.n); n != 1 {
+		t.Errorf("Expected one retrieval of the token, received %d", n)
+	}
+}
+
+// TestRefreshToken tests the function named refreshToken.
+func TestRefreshToken(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		cache = &tokenCache{entries: make(map[string]*tokenEntry)}
+		src   = &blockTokenSource{release: make(chan struct{})}
+		ts    = &sharedTokenSource{key: "k3y", src: ReuseTokenSource(nil, src), cache: cache}
+	)
+	close(src.release)
+
+	for i, exp := range []int32{1, 1, 2, 2} {
+		var err error
+		if i == 2 {
+			_, err = refreshToken(ctx, ts)
+		} else {
+			_, err = ts.Token(ctx)
+		}
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if n := atomic.LoadInt32(&src.n); n != exp {
+			t.Errorf("%d. Expected %d retrievals of the token, received %d", i, exp, n)
+		}
+	}
+
+	// Without cache, the token source is called.
+	if _, err := refreshToken(ctx, src); err != nil || atomic.LoadInt32(&src.n) != 3 {
+		t.Errorf("Expected a new token of the source, received %v", err)
+	}
+}
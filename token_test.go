@@ -0,0 +1,192 @@
+package awql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	awql "github.com/rvflash/awql-driver"
+)
+
+// countTokenSource returns a new token on each call and counts them.
+type countTokenSource struct {
+	n   int
+	err error
+}
+
+// Token implements the awql.TokenSource interface.
+func (s *countTokenSource) Token(_ context.Context) (*awql.AuthToken, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.n++
+	return &awql.AuthToken{
+		AccessToken: "ya29." + string(rune('A'+s.n)),
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(time.Hour),
+	}, nil
+}
+
+// TestStaticTokenSource tests the function named StaticTokenSource.
+func TestStaticTokenSource(t *testing.T) {
+	var staticTests = []struct {
+		tk  *awql.AuthToken
+		err error
+	}{
+		{tk: nil, err: awql.ErrBadToken},
+		{tk: &awql.AuthToken{}, err: awql.ErrBadToken},
+		{tk: &awql.AuthToken{AccessToken: "ya29.A", Expiry: time.Now().Add(-time.Hour)}, err: awql.ErrBadToken},
+		{tk: &awql.AuthToken{AccessToken: "ya29.A", TokenType: "Bearer"}},
+		{tk: &awql.AuthToken{AccessToken: "ya29.A", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}},
+	}
+	for i, st := range staticTests {
+		tk, err := awql.StaticTokenSource(st.tk).Token(context.Background())
+		if err != st.err {
+			t.Errorf("%d. Expected %v as error, received %v", i, st.err, err)
+		}
+		if err == nil && tk.String() != "Bearer ya29.A" {
+			t.Errorf("%d. Expected the static token, received %v", i, tk)
+		}
+	}
+}
+
+// TestReuseTokenSource tests the function named ReuseTokenSource.
+func TestReuseTokenSource(t *testing.T) {
+	var (
+		ctx = context.Background()
+		src = &countTokenSource{}
+	)
+	ts := awql.ReuseTokenSource(&awql.AuthToken{AccessToken: "ya29.A", Expiry: time.Now()}, src)
+	for i := 0; i < 3; i++ {
+		tk, err := ts.Token(ctx)
+		if err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+		if tk.AccessToken != "ya29.B" {
+			t.Errorf("%d. Expected the first new token, received %v", i, tk.AccessToken)
+		}
+	}
+	if src.n != 1 {
+		t.Errorf("Expected one new token, received %d", src.n)
+	}
+	ts = awql.ReuseTokenSource(nil, &countTokenSource{err: errors.New("oops")})
+	if _, err := ts.Token(ctx); err == nil {
+		t.Error("Expected the error of the source")
+	}
+}
+
+// TestRefreshTokenSource tests the function named RefreshTokenSource.
+func TestRefreshTokenSource(t *testing.T) {
+	var (
+		rqs []*http.Request
+		key = awql.AuthKey{ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/R3Fr35h-70k3n"}
+		ctx = context.Background()
+	)
+	var refreshTests = []struct {
+		client *http.Client
+		key    awql.AuthKey
+		err    error
+	}{
+		{client: newClient(http.StatusOK, "", nil), err: awql.ErrBadToken},
+		{client: newClient(http.StatusBadRequest, `{"error":"invalid_grant"}`, nil), key: key, err: awql.ErrAPIAuth},
+		{client: newClient(http.StatusOK, `{"access_token":"ya29.A"}`, nil), key: key, err: awql.ErrBadToken},
+		{
+			client: newClient(http.StatusOK, `{"access_token":"ya29.A","token_type":"Bearer","expires_in":3600}`, &rqs),
+			key:    key,
+		},
+	}
+	for i, rt := range refreshTests {
+		tk, err := awql.RefreshTokenSource(rt.client, rt.key).Token(ctx)
+		if !errors.Is(err, rt.err) {
+			t.Errorf("%d. Expected %v as error, received %v", i, rt.err, err)
+		}
+		if err == nil && (tk.String() != "Bearer ya29.A" || !tk.Valid()) {
+			t.Errorf("%d. Expected a valid token, received %v", i, tk)
+		}
+	}
+	if len(rqs) != 1 {
+		t.Fatalf("Expected one token request, received %d", len(rqs))
+	}
+	if err := rqs[0].ParseForm(); err != nil || rqs[0].PostForm.Get("refresh_token") != key.RefreshToken {
+		t.Errorf("Expected the refresh token in the request, received %v", rqs[0].PostForm)
+	}
+}
+
+// TestWithTokenSource tests the usage of a token source shared by the connections.
+func TestWithTokenSource(t *testing.T) {
+	var (
+		rqs []*http.Request
+		src = &countTokenSource{}
+	)
+	db := sql.OpenDB(newConnector(
+		t, &awql.Dsn{AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN", AccessToken: "ya29.AcC3s57okeN"},
+		awql.WithHTTPClient(newClient(http.StatusOK, "Account\nRv\n", &rqs)),
+		awql.WithTokenSource(awql.ReuseTokenSource(nil, src)),
+	))
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		var name string
+		if err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name); err != nil {
+			t.Fatalf("%d. Expected no error, received %v", i, err)
+		}
+	}
+	for i, rq := range rqs {
+		if h := rq.Header.Get("Authorization"); h != "Bearer ya29.B" {
+			t.Errorf("%d. Expected the token of the source, received %v", i, h)
+		}
+	}
+	if src.n != 1 {
+		t.Errorf("Expected one token of the source, received %d", src.n)
+	}
+}
+
+// TestSharedTokenSource tests the access token shared by the connections using the same keys.
+func TestSharedTokenSource(t *testing.T) {
+	var n int32
+	client := &http.Client{
+		Transport: roundTripFunc(func(rq *http.Request) (*http.Response, error) {
+			body := "Account\nRv\n"
+			if rq.URL.Host == "accounts.google.com" {
+				atomic.AddInt32(&n, 1)
+				body = `{"access_token":"ya29.S","token_type":"Bearer","expires_in":3600}`
+			} else if h := rq.Header.Get("Authorization"); h != "Bearer ya29.S" {
+				t.Errorf("Expected the shared token, received %v", h)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    rq,
+			}, nil
+		}),
+	}
+	dsn := &awql.Dsn{
+		AdwordsID: "123-456-7890", DeveloperToken: "dEve1op3er7okeN",
+		ClientID: "c1i3n7iD", ClientSecret: "c1ien753cr37", RefreshToken: "1/Sh4r3d",
+	}
+	db := sql.OpenDB(newConnector(t, dsn, awql.WithHTTPClient(client)))
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for k := 0; k < 20; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var name string
+			if err := db.QueryRow("SELECT AccountDescriptiveName FROM ACCOUNT_PERFORMANCE_REPORT").Scan(&name); err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n != 1 {
+		t.Errorf("Expected one token request, received %d", n)
+	}
+}
@@ -0,0 +1,124 @@
+package awql
+
+import (
+	"strings"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// Messages of the validation errors.
+const (
+	msgUnknownField  = "unknown field"
+	msgNotSelectable = "field not selectable"
+	msgNotFilterable = "field not filterable"
+	msgIncompatible  = "incompatible field"
+)
+
+// validate checks the statement parsed from the query q against the catalog of the API version.
+// The reports unknown by the catalog are not checked, the Adwords API supporting more reports.
+func validate(q, version string, st *parser.SelectStmt) error {
+	r, ok := lookupReport(version, st.From.Name)
+	if !ok {
+		return nil
+	}
+	return r.validate(q, st)
+}
+
+// validate checks that the selected fields of the statement parsed from the query q can be selected,
+// the filtered ones filtered, and that they are compatible. The catalog not listing all the fields,
+// a field missing from the report is only rejected if it differs in case from one of its fields,
+// the field names of the Adwords API being case sensitive.
+// The first invalid field is returned as QueryError.
+func (r *reportDef) validate(q string, st *parser.SelectStmt) error {
+	var (
+		used []*parser.Ident
+		fail = func(msg string, id *parser.Ident) error {
+			return newSyntaxError(parser.NewError(q, msg, id.Name, id.Offset))
+		}
+	)
+	// check returns the field of the column, nil for COUNT(*), an alias or a field missing from the catalog.
+	check := func(c *parser.Column, aliased bool) (*field, error) {
+		if c.Field.Name == "*" || aliased && aliasIndex(st.Fields, c) >= 0 {
+			return nil, nil
+		}
+		f, ok := r.field(c.Field.Name)
+		if !ok {
+			if r.misspelled(c.Field.Name) {
+				return nil, fail(msgUnknownField, c.Field)
+			}
+			return nil, nil
+		}
+		for _, u := range used {
+			if u.Name != f.Name && (inFields(f.ExclusiveFields, u.Name) || inExclusiveFields(r, u.Name, f.Name)) {
+				return nil, fail(msgIncompatible, c.Field)
+			}
+		}
+		used = append(used, c.Field)
+		return f, nil
+	}
+	for _, c := range st.Fields {
+		f, err := check(c, false)
+		if err != nil {
+			return err
+		}
+		if f != nil && f.NotSelectable {
+			return fail(msgNotSelectable, c.Field)
+		}
+	}
+	for _, c := range st.Where {
+		f, err := check(c.Field, false)
+		if err != nil {
+			return err
+		}
+		if f != nil && f.NotFilterable {
+			return fail(msgNotFilterable, c.Field.Field)
+		}
+	}
+	// The fields only used locally are also selected.
+	var cols []*parser.Column
+	for _, id := range st.GroupBy {
+		cols = append(cols, &parser.Column{Field: id})
+	}
+	for _, c := range st.Having {
+		cols = append(cols, c.Field)
+	}
+	for _, o := range st.OrderBy {
+		cols = append(cols, o.Field)
+	}
+	for _, c := range cols {
+		f, err := check(c, true)
+		if err != nil {
+			return err
+		}
+		if f != nil && f.NotSelectable {
+			return fail(msgNotSelectable, c.Field)
+		}
+	}
+	return nil
+}
+
+// misspelled returns true if the name only differs in case from the name of a field of the report.
+func (r *reportDef) misspelled(name string) bool {
+	for n := range r.fields {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inExclusiveFields returns true if the field named a of the report excludes the field named b.
+func inExclusiveFields(r *reportDef, a, b string) bool {
+	f, ok := r.field(a)
+	return ok && inFields(f.ExclusiveFields, b)
+}
+
+// inFields returns true if the name is in the list.
+func inFields(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package awql
+
+import (
+	"testing"
+
+	"github.com/rvflash/awql-driver/parser"
+)
+
+// TestReportDef_Validate tests the validation of the queries against a report definition.
+func TestReportDef_Validate(t *testing.T) {
+	r := &reportDef{Name: "R", fields: map[string]*field{
+		"Id":     {Name: "Id"},
+		"Name":   {Name: "Name", NotFilterable: true},
+		"Secret": {Name: "Secret", NotSelectable: true},
+		"Date":   {Name: "Date", ExclusiveFields: []string{"Hour"}},
+		"Hour":   {Name: "Hour"},
+		"Cost":   {Name: "Cost"},
+	}}
+	var validateTests = []struct {
+		q   string
+		err string
+	}{
+		{q: "SELECT Id, Name, Date, Cost FROM R WHERE Id > 1 AND Secret = 'a' ORDER BY Cost"},
+		{q: "SELECT Id AS id, SUM(Cost) AS cost, COUNT(*) FROM R GROUP BY id HAVING cost > 1 ORDER BY cost DESC"},
+		{q: "SELECT Id, Oops FROM R WHERE Oops = 1 GROUP BY Oops ORDER BY Oops"},
+		{q: "SELECT Id, cost FROM R", err: "QueryError.UNKNOWN_FIELD (cost) at position 12"},
+		{q: "SELECT Id FROM R WHERE ID = 1", err: "QueryError.UNKNOWN_FIELD (ID) at position 24"},
+		{q: "SELECT Id FROM R ORDER BY cost", err: "QueryError.UNKNOWN_FIELD (cost) at position 27"},
+		{q: "SELECT Id FROM R GROUP BY date", err: "QueryError.UNKNOWN_FIELD (date) at position 27"},
+		{q: "SELECT Secret FROM R", err: "QueryError.FIELD_NOT_SELECTABLE (Secret) at position 8"},
+		{q: "SELECT Id FROM R ORDER BY Secret", err: "QueryError.FIELD_NOT_SELECTABLE (Secret) at position 27"},
+		{q: "SELECT Id FROM R WHERE Name = 'a'", err: "QueryError.FIELD_NOT_FILTERABLE (Name) at position 24"},
+		{q: "SELECT Date, Id, Hour FROM R", err: "QueryError.INCOMPATIBLE_FIELD (Hour) at position 18"},
+		{q: "SELECT Hour FROM R WHERE Date = 20170101", err: "QueryError.INCOMPATIBLE_FIELD (Date) at position 26"},
+	}
+	for i, vt := range validateTests {
+		st, err := parser.Parse(vt.q)
+		if err != nil {
+			t.Fatalf("%d. Expected no syntax error, received %v", i, err)
+		}
+		err = r.validate(vt.q, st.(*parser.SelectStmt))
+		if vt.err == "" && err != nil {
+			t.Errorf("%d. Expected no error with %s, received %v", i, vt.q, err)
+		} else if vt.err != "" && (err == nil || err.Error() != vt.err) {
+			t.Errorf("%d. Expected %s with %s, received %v", i, vt.err, vt.q, err)
+		}
+	}
+}
+
+// TestValidate tests the validation of the queries against the catalog of an API version.
+func TestValidate(t *testing.T) {
+	var validateTests = []struct {
+		version, q, err string
+	}{
+		{version: APIVersion, q: "SELECT CampaignName, Clicks FROM CAMPAIGN_PERFORMANCE_REPORT"},
+		{version: APIVersion, q: "SELECT CampaignName, AdvertisingChannelType FROM CAMPAIGN_PERFORMANCE_REPORT"},
+		{version: APIVersion, q: "SELECT ConversionTypeName, Conversions FROM ACCOUNT_PERFORMANCE_REPORT"},
+		{version: APIVersion, q: "SELECT CampaignName FROM LABEL_REPORT"},
+		{version: "v201710", q: "SELECT Oops FROM CAMPAIGN_PERFORMANCE_REPORT"},
+		{version: APIVersion, q: "SELECT CampaignName, CampaignGroupId FROM CAMPAIGN_PERFORMANCE_REPORT"},
+		{
+			version: APIVersion,
+			q:       "SELECT CampaignName, clicks FROM CAMPAIGN_PERFORMANCE_REPORT",
+			err:     "QueryError.UNKNOWN_FIELD (clicks) at position 22",
+		},
+		{
+			version: APIVersion,
+			q:       "SELECT ConversionTypeName, Clicks FROM ACCOUNT_PERFORMANCE_REPORT",
+			err:     "QueryError.INCOMPATIBLE_FIELD (Clicks) at position 28",
+		},
+		{
+			version: APIVersion,
+			q:       "SELECT Cost FROM CAMPAIGN_PERFORMANCE_REPORT WHERE ExternalConversionSource = 'WEBPAGE'",
+			err:     "QueryError.INCOMPATIBLE_FIELD (ExternalConversionSource) at position 52",
+		},
+	}
+	for i, vt := range validateTests {
+		st, _ := parser.Parse(vt.q)
+		err := validate(vt.q, vt.version, st.(*parser.SelectStmt))
+		if vt.err == "" && err != nil {
+			t.Errorf("%d. Expected no error with %s, received %v", i, vt.q, err)
+		} else if vt.err != "" && (err == nil || err.Error() != vt.err) {
+			t.Errorf("%d. Expected %s with %s, received %v", i, vt.err, vt.q, err)
+		}
+	}
+}